@@ -0,0 +1,145 @@
+package apps
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	. "github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Admin buildpack management", func() {
+	var (
+		appName        string
+		appPath        string
+		tmpdir         string
+		buildpackNames []string
+	)
+
+	matchingFilename := func(appName string) string {
+		return fmt.Sprintf("managed-buildpack-match-%s", appName)
+	}
+
+	updateBuildpack := func(buildpackName string, args ...string) *Session {
+		var session *Session
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			session = cf.Cf(append([]string{"update-buildpack", buildpackName}, args...)...).Wait(DEFAULT_TIMEOUT)
+		})
+		return session
+	}
+
+	expectStagedWith := func(staging *Session, buildpackName string) {
+		Expect(staging).To(Exit(0))
+		Expect(staging).To(Say("Staging with the buildpack for " + buildpackName))
+		Eventually(func() string {
+			return helpers.CurlAppRoot(appName)
+		}, DEFAULT_TIMEOUT).Should(ContainSubstring("running with the buildpack for " + buildpackName))
+	}
+
+	BeforeEach(func() {
+		appName = PrefixedRandomName("CATS-APP-")
+		buildpackNames = nil
+
+		var err error
+		tmpdir, err = ioutil.TempDir("", "managed-buildpacks")
+		Expect(err).ToNot(HaveOccurred())
+		appPath, err = ioutil.TempDir(tmpdir, "matching-app")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = os.Create(path.Join(appPath, matchingFilename(appName)))
+		Expect(err).ToNot(HaveOccurred())
+
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			for position := 1; position <= 3; position++ {
+				buildpackName := RandomName()
+				buildpackArchivePath := createMatchingBuildpackArchive(tmpdir, buildpackName, matchingFilename(appName))
+
+				createBuildpack := cf.Cf("create-buildpack", buildpackName, buildpackArchivePath, fmt.Sprint(position)).Wait(DEFAULT_TIMEOUT)
+				if createBuildpack.ExitCode() == 0 {
+					buildpackNames = append(buildpackNames, buildpackName)
+				}
+				Expect(createBuildpack).To(Exit(0))
+			}
+		})
+
+		Expect(cf.Cf("push", appName,
+			"--no-start",
+			"-m", DEFAULT_MEMORY_LIMIT,
+			"-p", appPath,
+			"-d", config.AppsDomain,
+		).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		app_helpers.SetBackend(appName)
+	})
+
+	AfterEach(func() {
+		app_helpers.AppReport(appName, DEFAULT_TIMEOUT)
+
+		// Every buildpack that was created is deleted, whatever state a
+		// failed spec left it in, before the failures are reported.
+		var failedDeletions []string
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			for _, buildpackName := range buildpackNames {
+				cf.Cf("update-buildpack", buildpackName, "--unlock").Wait(DEFAULT_TIMEOUT)
+				if cf.Cf("delete-buildpack", buildpackName, "-f").Wait(DEFAULT_TIMEOUT).ExitCode() != 0 {
+					failedDeletions = append(failedDeletions, buildpackName)
+				}
+			}
+		})
+
+		os.RemoveAll(tmpdir)
+
+		Expect(cf.Cf("delete", appName, "-f", "-r").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(failedDeletions).To(BeEmpty(), "failed to delete buildpacks")
+	})
+
+	It("detects with the matching buildpack in the lowest position, following reordering", func() {
+		expectStagedWith(cf.Cf("start", appName).Wait(CF_PUSH_TIMEOUT), buildpackNames[0])
+
+		By("moving the last buildpack to the first position")
+		Expect(updateBuildpack(buildpackNames[2], "-i", "1")).To(Exit(0))
+		expectStagedWith(cf.Cf("restage", appName).Wait(CF_PUSH_TIMEOUT), buildpackNames[2])
+	})
+
+	It("never stages with a disabled buildpack", func() {
+		Expect(updateBuildpack(buildpackNames[0], "--disable")).To(Exit(0))
+
+		By("skipping it during detection")
+		expectStagedWith(cf.Cf("start", appName).Wait(CF_PUSH_TIMEOUT), buildpackNames[1])
+
+		By("not using it when it is requested explicitly")
+		push := cf.Cf("push", appName, "-b", buildpackNames[0], "-p", appPath).Wait(CF_PUSH_TIMEOUT)
+		Expect(push).To(Exit(1))
+		Expect(push).NotTo(Say("Staging with the buildpack for " + buildpackNames[0]))
+		Expect(push).To(Say("(?i)buildpack.*disabled"))
+		Expect(helpers.CurlAppRoot(appName)).To(ContainSubstring("running with the buildpack for " + buildpackNames[1]))
+
+		By("detecting with it again once it is enabled")
+		Expect(updateBuildpack(buildpackNames[0], "--enable")).To(Exit(0))
+		expectStagedWith(cf.Cf("push", appName, "-b", "default", "-p", appPath).Wait(CF_PUSH_TIMEOUT), buildpackNames[0])
+	})
+
+	It("rejects updates to the bits of a locked buildpack", func() {
+		Expect(updateBuildpack(buildpackNames[0], "--lock")).To(Exit(0))
+
+		replacementArchivePath := createMatchingBuildpackArchive(tmpdir, "a replacement", matchingFilename(appName))
+		update := updateBuildpack(buildpackNames[0], "-p", replacementArchivePath)
+		Expect(update).To(Exit(1))
+		Expect(update).To(Say("(?i)locked"))
+
+		By("staging with its original bits")
+		expectStagedWith(cf.Cf("start", appName).Wait(CF_PUSH_TIMEOUT), buildpackNames[0])
+
+		By("accepting updates once it is unlocked")
+		Expect(updateBuildpack(buildpackNames[0], "--unlock")).To(Exit(0))
+		Expect(updateBuildpack(buildpackNames[0], "-p", replacementArchivePath)).To(Exit(0))
+		expectStagedWith(cf.Cf("restage", appName).Wait(CF_PUSH_TIMEOUT), "a replacement")
+	})
+})
@@ -0,0 +1,277 @@
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/matchers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/v3_helpers"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+)
+
+// appConfiguration is everything create-app-manifest is expected to capture
+// about an app, in a form that is comparable between two apps. Routes are
+// compared separately, since each app has its own.
+type appConfiguration struct {
+	Buildpack               string
+	Memory                  int
+	DiskQuota               int
+	Instances               int
+	HealthCheckType         string
+	HealthCheckHttpEndpoint string
+	Environment             map[string]string
+	ServiceInstanceGuids    []string
+	WebProcess              webProcessConfiguration
+}
+
+type appRoute struct {
+	Guid       string `json:"-"`
+	Host       string `json:"host"`
+	DomainGuid string `json:"domain_guid"`
+	Path       string `json:"path"`
+	Port       int    `json:"port"`
+}
+
+type webProcessConfiguration struct {
+	Instances           int
+	MemoryInMb          int
+	DiskInMb            int
+	HealthCheckType     string
+	HealthCheckEndpoint string
+}
+
+var _ = Describe(deaUnsupportedTag+"Application manifest round trip", func() {
+	var (
+		sourceAppName string
+		copyAppName   string
+		serviceName   string
+		manifestDir   string
+	)
+
+	BeforeEach(func() {
+		sourceAppName = generator.PrefixedRandomName("CATS-APP-")
+		copyAppName = generator.PrefixedRandomName("CATS-APP-")
+		serviceName = generator.PrefixedRandomName("CATS-SVC-")
+
+		var err error
+		manifestDir, err = ioutil.TempDir("", "cats-app-manifest")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		for _, appName := range []string{sourceAppName, copyAppName} {
+			app_helpers.AppReport(appName, DEFAULT_TIMEOUT)
+			Expect(cf.Cf("delete", appName, "-f", "-r").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		}
+		Expect(cf.Cf("delete-service", serviceName, "-f").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(os.RemoveAll(manifestDir)).To(Succeed())
+	})
+
+	It("pushes a copy of an app with identical configuration from the manifest it generates", func() {
+		By("configuring the source app one operation at a time")
+		Expect(cf.Cf("push", sourceAppName,
+			"--no-start",
+			"-b", config.RubyBuildpackName,
+			"-m", DEFAULT_MEMORY_LIMIT,
+			"-p", assets.NewAssets().Dora,
+			"-d", config.AppsDomain,
+		).Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+
+		Expect(cf.Cf("set-env", sourceAppName, "CATS_ROUND_TRIP", "true").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("set-env", sourceAppName, "CATS_JSON", `{"nested":["value"]}`).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+		expectedRoutes := 2
+		Expect(cf.Cf("map-route", sourceAppName, config.AppsDomain, "--hostname", sourceAppName, "--path", "/round/trip").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		if appsConfig.TcpDomain != "" {
+			expectedRoutes++
+			Expect(cf.Cf("map-route", sourceAppName, appsConfig.TcpDomain, "--random-port").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		}
+
+		Expect(cf.Cf("create-user-provided-service", serviceName, "-p", `{"uri":"cats://round-trip"}`).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("bind-service", sourceAppName, serviceName).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+		Expect(cf.Cf("set-health-check", sourceAppName, "http", "--endpoint", "/id").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("scale", sourceAppName, "-i", "2", "-m", "128M", "-k", "512M", "-f").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+		sourceRoutes := getAppRoutes(sourceAppName)
+		Expect(sourceRoutes).To(HaveLen(expectedRoutes))
+
+		By("generating a manifest and pointing its routes at the new app")
+		manifestPath := filepath.Join(manifestDir, "manifest.yml")
+		Expect(cf.Cf("create-app-manifest", sourceAppName, "-p", manifestPath).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+		manifest, err := ioutil.ReadFile(manifestPath)
+		Expect(err).NotTo(HaveOccurred())
+		copyManifest := strings.Replace(string(manifest), sourceAppName, copyAppName, -1)
+
+		// TCP routes have no host, so the copy gets a port of its own instead.
+		copyPorts := map[int]int{}
+		for _, route := range sourceRoutes {
+			if route.Port == 0 {
+				continue
+			}
+			createRoute := cf.Cf("create-route", context.RegularUserContext().Space, appsConfig.TcpDomain, "--random-port").Wait(DEFAULT_TIMEOUT)
+			Expect(createRoute).To(Exit(0))
+			port := regexp.MustCompile(regexp.QuoteMeta(appsConfig.TcpDomain) + `:(\d+)`).FindSubmatch(createRoute.Out.Contents())
+			Expect(port).NotTo(BeNil())
+			copyPorts[route.Port], err = strconv.Atoi(string(port[1]))
+			Expect(err).NotTo(HaveOccurred())
+
+			copyManifest = strings.Replace(copyManifest,
+				fmt.Sprintf("%s:%d", appsConfig.TcpDomain, route.Port),
+				fmt.Sprintf("%s:%d", appsConfig.TcpDomain, copyPorts[route.Port]),
+				-1)
+		}
+		Expect(ioutil.WriteFile(manifestPath, []byte(copyManifest), 0644)).To(Succeed())
+
+		By("pushing a new app from the manifest")
+		Expect(cf.Cf("push", copyAppName,
+			"--no-start",
+			"-f", manifestPath,
+			"-p", assets.NewAssets().Dora,
+		).Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+
+		sourceConfiguration := getAppConfiguration(sourceAppName)
+		Expect(sourceConfiguration.ServiceInstanceGuids).To(HaveLen(1))
+		Expect(getAppConfiguration(copyAppName)).To(matchers.EqualFields(sourceConfiguration))
+
+		var expectedCopyRoutes []appRoute
+		for _, route := range sourceRoutes {
+			if route.Host == sourceAppName {
+				route.Host = copyAppName
+			}
+			if route.Port != 0 {
+				route.Port = copyPorts[route.Port]
+			}
+			expectedCopyRoutes = append(expectedCopyRoutes, route)
+		}
+		sort.Sort(appRoutesByAddress(expectedCopyRoutes))
+
+		copyRoutes := getAppRoutes(copyAppName)
+		Expect(routeAddresses(copyRoutes)).To(Equal(routeAddresses(expectedCopyRoutes)))
+		for _, route := range copyRoutes {
+			Expect(routeGuids(sourceRoutes)).NotTo(ContainElement(route.Guid))
+		}
+	})
+})
+
+func getAppConfiguration(appName string) appConfiguration {
+	appGuid := app_helpers.GetAppGuid(appName)
+
+	var app struct {
+		Entity struct {
+			Buildpack               string            `json:"buildpack"`
+			Memory                  int               `json:"memory"`
+			DiskQuota               int               `json:"disk_quota"`
+			Instances               int               `json:"instances"`
+			HealthCheckType         string            `json:"health_check_type"`
+			HealthCheckHttpEndpoint string            `json:"health_check_http_endpoint"`
+			Environment             map[string]string `json:"environment_json"`
+		} `json:"entity"`
+	}
+	curlJson(fmt.Sprintf("/v2/apps/%s", appGuid), &app)
+
+	configuration := appConfiguration{
+		Buildpack:               app.Entity.Buildpack,
+		Memory:                  app.Entity.Memory,
+		DiskQuota:               app.Entity.DiskQuota,
+		Instances:               app.Entity.Instances,
+		HealthCheckType:         app.Entity.HealthCheckType,
+		HealthCheckHttpEndpoint: app.Entity.HealthCheckHttpEndpoint,
+		Environment:             app.Entity.Environment,
+	}
+
+	var bindings struct {
+		Resources []struct {
+			Entity struct {
+				ServiceInstanceGuid string `json:"service_instance_guid"`
+			} `json:"entity"`
+		} `json:"resources"`
+	}
+	curlJson(fmt.Sprintf("/v2/apps/%s/service_bindings", appGuid), &bindings)
+	for _, binding := range bindings.Resources {
+		configuration.ServiceInstanceGuids = append(configuration.ServiceInstanceGuids, binding.Entity.ServiceInstanceGuid)
+	}
+	sort.Strings(configuration.ServiceInstanceGuids)
+
+	web := v3_helpers.GetProcessByType(v3_helpers.GetProcesses(appGuid, appName), "web")
+	configuration.WebProcess = webProcessConfiguration{
+		Instances:           web.Instances,
+		MemoryInMb:          web.MemoryInMb,
+		DiskInMb:            web.DiskInMb,
+		HealthCheckType:     web.HealthCheck.Type,
+		HealthCheckEndpoint: web.HealthCheck.Data.Endpoint,
+	}
+
+	return configuration
+}
+
+// getAppRoutes returns the routes mapped to an app, sorted by address.
+func getAppRoutes(appName string) []appRoute {
+	var routes struct {
+		Resources []struct {
+			Metadata struct {
+				Guid string `json:"guid"`
+			} `json:"metadata"`
+			Entity appRoute `json:"entity"`
+		} `json:"resources"`
+	}
+	curlJson(fmt.Sprintf("/v2/apps/%s/routes", app_helpers.GetAppGuid(appName)), &routes)
+
+	var appRoutes []appRoute
+	for _, route := range routes.Resources {
+		route.Entity.Guid = route.Metadata.Guid
+		appRoutes = append(appRoutes, route.Entity)
+	}
+	sort.Sort(appRoutesByAddress(appRoutes))
+	return appRoutes
+}
+
+// routeAddresses strips the route guids, leaving the host, domain, path and
+// port of each route.
+func routeAddresses(routes []appRoute) []appRoute {
+	var addresses []appRoute
+	for _, route := range routes {
+		route.Guid = ""
+		addresses = append(addresses, route)
+	}
+	return addresses
+}
+
+func routeGuids(routes []appRoute) []string {
+	var guids []string
+	for _, route := range routes {
+		guids = append(guids, route.Guid)
+	}
+	return guids
+}
+
+func curlJson(path string, response interface{}) {
+	session := cf.Cf("curl", path)
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+	Expect(json.Unmarshal(session.Out.Contents(), response)).To(Succeed())
+}
+
+type appRoutesByAddress []appRoute
+
+func (routes appRoutesByAddress) Len() int      { return len(routes) }
+func (routes appRoutesByAddress) Swap(i, j int) { routes[i], routes[j] = routes[j], routes[i] }
+func (routes appRoutesByAddress) Less(i, j int) bool {
+	return fmt.Sprintf("%s/%s:%d%s", routes[i].DomainGuid, routes[i].Host, routes[i].Port, routes[i].Path) <
+		fmt.Sprintf("%s/%s:%d%s", routes[j].DomainGuid, routes[j].Host, routes[j].Port, routes[j].Path)
+}
@@ -0,0 +1,197 @@
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	. "github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+	archive_helpers "github.com/pivotal-golang/archiver/extractor/test_helper"
+)
+
+var _ = Describe("Buildpacks bound to a stack", func() {
+	var (
+		appName        string
+		appPath        string
+		tmpdir         string
+		buildpackGuids map[string]string
+	)
+
+	matchingFilename := func(appName string) string {
+		return fmt.Sprintf("stack-buildpack-match-%s", appName)
+	}
+
+	BeforeEach(func() {
+		appName = PrefixedRandomName("CATS-APP-")
+
+		var err error
+		tmpdir, err = ioutil.TempDir("", "stack-buildpacks")
+		Expect(err).ToNot(HaveOccurred())
+		appPath, err = ioutil.TempDir(tmpdir, "matching-app")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = os.Create(path.Join(appPath, matchingFilename(appName)))
+		Expect(err).ToNot(HaveOccurred())
+
+		buildpackGuids = map[string]string{}
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			for _, stackName := range appsConfig.Stacks {
+				buildpackArchivePath := createMatchingBuildpackArchive(tmpdir, stackName, matchingFilename(appName))
+				buildpackGuids[stackName] = app_helpers.CreateStackBuildpack(RandomName(), stackName, buildpackArchivePath, 1)
+			}
+		})
+	})
+
+	AfterEach(func() {
+		app_helpers.AppReport(appName, DEFAULT_TIMEOUT)
+
+		Expect(cf.Cf("delete", appName, "-f", "-r").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			for _, buildpackGuid := range buildpackGuids {
+				app_helpers.DeleteBuildpack(buildpackGuid)
+			}
+		})
+
+		os.RemoveAll(tmpdir)
+	})
+
+	It("detects the buildpack bound to each stack when staging on it", func() {
+		for _, stackName := range appsConfig.Stacks {
+			By("staging on " + stackName)
+			Expect(cf.Cf("push", appName,
+				"--no-start",
+				"-m", DEFAULT_MEMORY_LIMIT,
+				"-p", appPath,
+				"-s", stackName,
+				"-d", config.AppsDomain,
+			).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			app_helpers.SetBackend(appName)
+
+			start := cf.Cf("start", appName).Wait(CF_PUSH_TIMEOUT)
+			Expect(start).To(Exit(0))
+			Expect(start).To(Say("Staging with the buildpack for " + stackName))
+
+			Eventually(func() string {
+				return helpers.CurlAppRoot(appName)
+			}, DEFAULT_TIMEOUT).Should(ContainSubstring("running with the buildpack for " + stackName))
+
+			session := cf.Cf("curl", fmt.Sprintf("/v2/apps/%s", app_helpers.GetAppGuid(appName)))
+			Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			var app struct {
+				Entity struct {
+					StackGuid             string `json:"stack_guid"`
+					DetectedBuildpackGuid string `json:"detected_buildpack_guid"`
+				} `json:"entity"`
+			}
+			Expect(json.Unmarshal(session.Out.Contents(), &app)).To(Succeed())
+			Expect(app.Entity.StackGuid).To(Equal(app_helpers.GetStackGuid(stackName)))
+			Expect(app.Entity.DetectedBuildpackGuid).To(Equal(buildpackGuids[stackName]))
+		}
+	})
+
+	Context("when the requested buildpack is not bound to the app's stack", func() {
+		var (
+			otherStackGuid     string
+			otherBuildpackName string
+			otherBuildpackGuid string
+		)
+
+		BeforeEach(func() {
+			otherBuildpackName = RandomName()
+
+			cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+				otherStackName := PrefixedRandomName("CATS-STACK-")
+				session := cf.Cf("curl", "/v2/stacks", "-X", "POST", "-d", fmt.Sprintf(`{"name":"%s"}`, otherStackName))
+				Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+				otherStackGuid = app_helpers.GetStackGuid(otherStackName)
+
+				buildpackArchivePath := createMatchingBuildpackArchive(tmpdir, otherStackName, matchingFilename(appName))
+				otherBuildpackGuid = app_helpers.CreateStackBuildpack(otherBuildpackName, otherStackName, buildpackArchivePath, 1)
+			})
+		})
+
+		AfterEach(func() {
+			cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+				app_helpers.DeleteBuildpack(otherBuildpackGuid)
+				Expect(cf.Cf("curl", fmt.Sprintf("/v2/stacks/%s", otherStackGuid), "-X", "DELETE").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			})
+		})
+
+		It("fails to stage, naming the buildpack and the stack", func() {
+			stackName := appsConfig.Stacks[0]
+
+			push := cf.Cf("push", appName,
+				"-b", otherBuildpackName,
+				"-m", DEFAULT_MEMORY_LIMIT,
+				"-p", appPath,
+				"-s", stackName,
+				"-d", config.AppsDomain,
+			).Wait(CF_PUSH_TIMEOUT)
+			Expect(push).NotTo(Exit(0))
+
+			output := string(push.Out.Contents()) + string(push.Err.Contents())
+			Expect(output).To(ContainSubstring(otherBuildpackName))
+			Expect(output).To(ContainSubstring(stackName))
+		})
+	})
+})
+
+// createMatchingBuildpackArchive creates a buildpack that detects apps
+// containing matchingFilename, and reports what it is for (e.g. a stack)
+// while staging and running.
+func createMatchingBuildpackArchive(dir, target, matchingFilename string) string {
+	buildpackPath, err := ioutil.TempDir(dir, "stack-buildpack")
+	Expect(err).ToNot(HaveOccurred())
+	buildpackArchivePath := path.Join(buildpackPath, "buildpack.zip")
+
+	archive_helpers.CreateZipArchive(buildpackArchivePath, []archive_helpers.ArchiveFile{
+		{
+			Name: "bin/compile",
+			Body: fmt.Sprintf(`#!/usr/bin/env bash
+
+sleep 5 # give loggregator time to start streaming the logs
+
+echo "Staging with the buildpack for %s"
+
+sleep 10
+`, target),
+		},
+		{
+			Name: "bin/detect",
+			Body: fmt.Sprintf(`#!/bin/bash
+
+if [ -f "${1}/%s" ]; then
+  echo Simple
+else
+  echo no
+  exit 1
+fi
+`, matchingFilename),
+		},
+		{
+			Name: "bin/release",
+			Body: fmt.Sprintf(`#!/usr/bin/env bash
+
+cat <<EOF
+---
+config_vars:
+  PATH: bin:/usr/local/bin:/usr/bin:/bin
+default_process_types:
+  web: while true; do { echo -e 'HTTP/1.1 200 OK\r\n'; echo "running with the buildpack for %s"; } | nc -l \$PORT; done
+EOF
+`, target),
+		},
+	})
+
+	return buildpackArchivePath
+}
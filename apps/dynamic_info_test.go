@@ -11,6 +11,7 @@ import (
 	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
 	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
 	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/matchers"
 )
 
 var _ = Describe("A running application", func() {
@@ -68,10 +69,16 @@ var _ = Describe("A running application", func() {
 		})
 
 		It("can be queried for state by instance", func() {
-			app := cf.Cf("app", appName).Wait(DEFAULT_TIMEOUT)
-			Expect(app).To(Exit(0))
-			Expect(app).To(Say("#0"))
-			Expect(app).To(Say("#1"))
+			appGuid := app_helpers.GetAppGuid(appName)
+			Eventually(func() app_helpers.AppStats {
+				return app_helpers.GetAppStats(appGuid)
+			}, DEFAULT_TIMEOUT).Should(HaveInstancesInState(2, "RUNNING"))
+
+			stats := app_helpers.GetAppStats(appGuid)
+			for _, index := range []string{"0", "1"} {
+				Expect(stats).To(HaveKey(index))
+				Expect(stats[index].Stats.Host).NotTo(BeEmpty())
+			}
 		})
 	})
 })
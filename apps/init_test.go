@@ -23,14 +23,36 @@ const diegoUnsupportedTag = "{NO_DIEGO_SUPPORT} "
 const deaUnsupportedTag = "{NO_DEA_SUPPORT} "
 
 var (
-	context helpers.SuiteContext
-	config  helpers.Config
+	context    helpers.SuiteContext
+	config     helpers.Config
+	appsConfig appsSuiteConfig
 )
 
+type appsSuiteConfig struct {
+	helpers.Config
+
+	TcpDomain string   `json:"tcp_domain"`
+	Stacks    []string `json:"stacks"`
+}
+
+func loadAppsConfig() appsSuiteConfig {
+	var suiteConfig appsSuiteConfig
+	err := helpers.Load(helpers.ConfigPath(), &suiteConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(suiteConfig.Stacks) == 0 {
+		suiteConfig.Stacks = []string{"cflinuxfs2"}
+	}
+	return suiteConfig
+}
+
 func TestApplications(t *testing.T) {
 	RegisterFailHandler(Fail)
 
 	config = helpers.LoadConfig()
+	appsConfig = loadAppsConfig()
 
 	if config.DefaultTimeout > 0 {
 		DEFAULT_TIMEOUT = config.DefaultTimeout * time.Second
@@ -0,0 +1,234 @@
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/v3_helpers"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+)
+
+type manifestAppEntity struct {
+	Name                    string            `json:"name"`
+	Memory                  int               `json:"memory"`
+	Instances               int               `json:"instances"`
+	DiskQuota               int               `json:"disk_quota"`
+	HealthCheckType         string            `json:"health_check_type"`
+	HealthCheckHttpEndpoint string            `json:"health_check_http_endpoint"`
+	Environment             map[string]string `json:"environment_json"`
+}
+
+var _ = Describe(deaUnsupportedTag+"Pushing from a manifest", func() {
+	var (
+		manifestDir  string
+		manifestPath string
+		appNames     []string
+	)
+
+	BeforeEach(func() {
+		var err error
+		manifestDir, err = ioutil.TempDir("", "cats-manifest")
+		Expect(err).NotTo(HaveOccurred())
+		manifestPath = filepath.Join(manifestDir, "manifest.yml")
+		appNames = nil
+	})
+
+	AfterEach(func() {
+		for _, appName := range appNames {
+			app_helpers.AppReport(appName, DEFAULT_TIMEOUT)
+			Expect(cf.Cf("delete", appName, "-f", "-r").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		}
+		Expect(os.RemoveAll(manifestDir)).To(Succeed())
+	})
+
+	newApp := func() app_helpers.ManifestApplication {
+		appName := generator.PrefixedRandomName("CATS-APP-")
+		appNames = append(appNames, appName)
+		// The manifest lives in a temporary directory, so a relative path
+		// would be resolved against that directory rather than the assets.
+		appPath, err := filepath.Abs(assets.NewAssets().Dora)
+		Expect(err).NotTo(HaveOccurred())
+		return app_helpers.ManifestApplication{
+			Name:      appName,
+			Path:      appPath,
+			Buildpack: config.RubyBuildpackName,
+			Memory:    DEFAULT_MEMORY_LIMIT,
+			Routes:    []string{fmt.Sprintf("%s.%s", appName, config.AppsDomain)},
+		}
+	}
+
+	push := func(args ...string) {
+		pushArgs := append([]string{"push", "-f", manifestPath}, args...)
+		Expect(cf.Cf(pushArgs...).Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+	}
+
+	It("pushes every application with its routes, environment and health check", func() {
+		first := newApp()
+		first.Instances = 2
+		first.Routes = append(first.Routes, fmt.Sprintf("%s-alias.%s", first.Name, config.AppsDomain))
+		first.Env = map[string]string{"CATS_MANIFEST": "first", "CATS_QUOTED": `"quoted": value`}
+		first.HealthCheckType = "http"
+		first.HealthCheckHttpEndpoint = "/id"
+
+		second := newApp()
+		second.Memory = "128M"
+		second.DiskQuota = "512M"
+		second.Env = map[string]string{"CATS_MANIFEST": "second"}
+		second.HealthCheckType = "port"
+
+		app_helpers.Manifest{Applications: []app_helpers.ManifestApplication{first, second}}.WriteFile(manifestPath)
+		push()
+
+		firstApp := getManifestAppEntity(first.Name)
+		Expect(firstApp.Instances).To(Equal(2))
+		Expect(firstApp.Memory).To(Equal(256))
+		Expect(firstApp.HealthCheckType).To(Equal("http"))
+		Expect(firstApp.HealthCheckHttpEndpoint).To(Equal("/id"))
+		Expect(firstApp.Environment).To(Equal(first.Env))
+
+		secondApp := getManifestAppEntity(second.Name)
+		Expect(secondApp.Instances).To(Equal(1))
+		Expect(secondApp.Memory).To(Equal(128))
+		Expect(secondApp.DiskQuota).To(Equal(512))
+		Expect(secondApp.HealthCheckType).To(Equal("port"))
+		Expect(secondApp.Environment).To(Equal(second.Env))
+
+		By("routing every declared route to its application")
+		Eventually(helpers.CurlingAppRoot(first.Name), DEFAULT_TIMEOUT).Should(ContainSubstring("Hi, I'm Dora!"))
+		Eventually(helpers.CurlingAppRoot(first.Name+"-alias"), DEFAULT_TIMEOUT).Should(ContainSubstring("Hi, I'm Dora!"))
+		Eventually(helpers.CurlingAppRoot(second.Name), DEFAULT_TIMEOUT).Should(ContainSubstring("Hi, I'm Dora!"))
+		Expect(helpers.CurlApp(first.Name, "/env/CATS_QUOTED")).To(Equal(`"quoted": value`))
+	})
+
+	It("binds the declared services", func() {
+		serviceName := generator.PrefixedRandomName("CATS-SVC-")
+		Expect(cf.Cf("create-user-provided-service", serviceName, "-p", `{"uri":"cats://manifest"}`).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		defer func() {
+			Expect(cf.Cf("delete-service", serviceName, "-f").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		}()
+
+		app := newApp()
+		app.Services = []string{serviceName}
+		app_helpers.Manifest{Applications: []app_helpers.ManifestApplication{app}}.WriteFile(manifestPath)
+		push("--no-start")
+
+		session := cf.Cf("curl", fmt.Sprintf("/v2/apps/%s/service_bindings", app_helpers.GetAppGuid(app.Name)))
+		Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		var bindings struct {
+			Resources []struct {
+				Entity struct {
+					ServiceInstanceGuid string `json:"service_instance_guid"`
+				} `json:"entity"`
+			} `json:"resources"`
+		}
+		Expect(json.Unmarshal(session.Out.Contents(), &bindings)).To(Succeed())
+
+		serviceGuid := cf.Cf("service", serviceName, "--guid").Wait(DEFAULT_TIMEOUT)
+		Expect(serviceGuid).To(Exit(0))
+		Expect(bindings.Resources).To(HaveLen(1))
+		Expect(bindings.Resources[0].Entity.ServiceInstanceGuid).To(Equal(strings.TrimSpace(string(serviceGuid.Out.Contents()))))
+	})
+
+	It("applies the attributes inherited from a parent manifest, letting the application override them", func() {
+		parentPath := filepath.Join(manifestDir, "parent.yml")
+		app_helpers.Manifest{
+			Shared: app_helpers.ManifestApplication{
+				Memory:    "128M",
+				Instances: 2,
+				Env:       map[string]string{"CATS_INHERITED": "parent"},
+			},
+		}.WriteFile(parentPath)
+
+		app := newApp()
+		app.Memory = ""
+		app.Instances = 1
+		app_helpers.Manifest{
+			Inherit:      parentPath,
+			Applications: []app_helpers.ManifestApplication{app},
+		}.WriteFile(manifestPath)
+		push("--no-start")
+
+		entity := getManifestAppEntity(app.Name)
+		Expect(entity.Memory).To(Equal(128))
+		Expect(entity.Instances).To(Equal(1))
+		Expect(entity.Environment).To(HaveKeyWithValue("CATS_INHERITED", "parent"))
+	})
+
+	It("substitutes variables from a vars file", func() {
+		varsPath := filepath.Join(manifestDir, "vars.yml")
+
+		app := newApp()
+		hostname := app.Name + "-vars"
+		app.Memory = "((memory))"
+		app.Routes = []string{"((hostname)).((domain))"}
+		app.Env = map[string]string{"CATS_VAR": "((greeting))"}
+		app_helpers.Manifest{Applications: []app_helpers.ManifestApplication{app}}.WriteFile(manifestPath)
+
+		app_helpers.WriteVarsFile(varsPath, map[string]interface{}{
+			"memory":   "128M",
+			"hostname": hostname,
+			"domain":   config.AppsDomain,
+			"greeting": "hello from a vars file",
+		})
+		push("--vars-file", varsPath)
+
+		entity := getManifestAppEntity(app.Name)
+		Expect(entity.Memory).To(Equal(128))
+		Expect(entity.Environment).To(HaveKeyWithValue("CATS_VAR", "hello from a vars file"))
+		Eventually(helpers.CurlingAppRoot(hostname), DEFAULT_TIMEOUT).Should(ContainSubstring("Hi, I'm Dora!"))
+	})
+
+	It("scales and health checks each process declared in the manifest", func() {
+		app := newApp()
+		app_helpers.Manifest{Applications: []app_helpers.ManifestApplication{app}}.WriteFile(manifestPath)
+		push("--no-start")
+
+		appGuid := app_helpers.GetAppGuid(app.Name)
+		spaceGuid := v3_helpers.GetSpaceGuidFromName(context.RegularUserContext().Space)
+
+		app.Path = ""
+		app.Buildpack = ""
+		app.Memory = ""
+		app.Processes = []app_helpers.ManifestProcess{{
+			Type:                    "web",
+			Instances:               2,
+			Memory:                  "128M",
+			HealthCheckType:         "http",
+			HealthCheckHttpEndpoint: "/id",
+		}}
+		v3_helpers.ApplyManifest(spaceGuid, app_helpers.Manifest{Applications: []app_helpers.ManifestApplication{app}}.String())
+
+		web := v3_helpers.GetProcessByType(v3_helpers.GetProcesses(appGuid, app.Name), "web")
+		Expect(web.Instances).To(Equal(2))
+		Expect(web.MemoryInMb).To(Equal(128))
+		Expect(web.HealthCheck.Type).To(Equal("http"))
+		Expect(web.HealthCheck.Data.Endpoint).To(Equal("/id"))
+
+		Expect(cf.Cf("start", app.Name).Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+		Eventually(helpers.CurlingAppRoot(app.Name), DEFAULT_TIMEOUT).Should(ContainSubstring("Hi, I'm Dora!"))
+	})
+})
+
+func getManifestAppEntity(appName string) manifestAppEntity {
+	session := cf.Cf("curl", fmt.Sprintf("/v2/apps/%s", app_helpers.GetAppGuid(appName)))
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var app struct {
+		Entity manifestAppEntity `json:"entity"`
+	}
+	Expect(json.Unmarshal(session.Out.Contents(), &app)).To(Succeed())
+	return app.Entity
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	forwardedUrlHeader = "X-CF-Forwarded-Url"
+	signatureHeader    = "X-CF-Proxy-Signature"
+	metadataHeader     = "X-CF-Proxy-Metadata"
+)
+
+type Behavior struct {
+	// Signature is either "" (forward untouched), "tamper" or "strip".
+	Signature    string `json:"signature"`
+	DelaySeconds int    `json:"delay_seconds"`
+}
+
+type RecordedRequest struct {
+	Method       string `json:"method"`
+	ForwardedUrl string `json:"forwarded_url"`
+	Signature    string `json:"signature"`
+	Metadata     string `json:"metadata"`
+}
+
+type routeService struct {
+	mutex     sync.Mutex
+	behavior  Behavior
+	requests  []RecordedRequest
+	transport http.RoundTripper
+}
+
+func main() {
+	service := &routeService{
+		requests: []RecordedRequest{},
+		transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+
+	fmt.Println("listening...")
+	err := http.ListenAndServe(":"+os.Getenv("PORT"), service)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ServeHTTP forwards requests sent by the gorouter, which always carry the
+// X-CF-Forwarded-Url header, and treats everything else as a request to
+// inspect or configure the route service.
+func (s *routeService) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.Header.Get(forwardedUrlHeader) != "" {
+		s.forward(res, req)
+		return
+	}
+
+	switch {
+	case req.URL.Path == "/requests" && req.Method == "GET":
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		writeJSON(res, s.requests)
+	case req.URL.Path == "/requests" && req.Method == "DELETE":
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.requests = []RecordedRequest{}
+		writeJSON(res, s.requests)
+	case req.URL.Path == "/behavior" && req.Method == "GET":
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		writeJSON(res, s.behavior)
+	case req.URL.Path == "/behavior" && (req.Method == "PUT" || req.Method == "POST"):
+		var behavior Behavior
+		err := json.NewDecoder(req.Body).Decode(&behavior)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.behavior = behavior
+		fmt.Printf("Behavior: %+v\n", behavior)
+		writeJSON(res, s.behavior)
+	default:
+		fmt.Fprintln(res, "configurable route service")
+	}
+}
+
+func (s *routeService) forward(res http.ResponseWriter, req *http.Request) {
+	recorded := RecordedRequest{
+		Method:       req.Method,
+		ForwardedUrl: req.Header.Get(forwardedUrlHeader),
+		Signature:    req.Header.Get(signatureHeader),
+		Metadata:     req.Header.Get(metadataHeader),
+	}
+	fmt.Printf("Request Headers: %s=%s %s=%s %s=%s\n",
+		forwardedUrlHeader, recorded.ForwardedUrl,
+		signatureHeader, recorded.Signature,
+		metadataHeader, recorded.Metadata,
+	)
+
+	s.mutex.Lock()
+	s.requests = append(s.requests, recorded)
+	behavior := s.behavior
+	s.mutex.Unlock()
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	outgoing, err := http.NewRequest(req.Method, recorded.ForwardedUrl, bytes.NewReader(body))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+		return
+	}
+	for name, values := range req.Header {
+		outgoing.Header[name] = values
+	}
+	outgoing.Host = outgoing.URL.Host
+
+	switch behavior.Signature {
+	case "tamper":
+		outgoing.Header.Set(signatureHeader, tamper(recorded.Signature))
+	case "strip":
+		outgoing.Header.Del(signatureHeader)
+	}
+
+	if behavior.DelaySeconds > 0 {
+		fmt.Printf("Delaying request for %d seconds\n", behavior.DelaySeconds)
+		time.Sleep(time.Duration(behavior.DelaySeconds) * time.Second)
+	}
+
+	response, err := s.transport.RoundTrip(outgoing)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+		return
+	}
+	fmt.Printf("Response Status: %d\n", response.StatusCode)
+	fmt.Printf("Response Body: %s\n", responseBody)
+
+	for name, values := range response.Header {
+		res.Header()[name] = values
+	}
+	res.WriteHeader(response.StatusCode)
+	io.Copy(res, bytes.NewReader(responseBody))
+}
+
+// tamper flips a bit in the middle of the decoded signature, so the result is
+// still well-formed but can no longer be decrypted by the gorouter.
+func tamper(signature string) string {
+	decoded, err := base64.URLEncoding.DecodeString(signature)
+	if err != nil || len(decoded) == 0 {
+		return signature + "tampered"
+	}
+	decoded[len(decoded)/2] ^= 0x01
+	return base64.URLEncoding.EncodeToString(decoded)
+}
+
+func writeJSON(res http.ResponseWriter, value interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(res).Encode(value)
+}
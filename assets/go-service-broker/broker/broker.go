@@ -0,0 +1,521 @@
+// Package broker is a service broker whose responses are configured through
+// its /config endpoint, using the same configuration as the Ruby broker in
+// assets/service_broker.
+package broker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Behavior is how the broker responds to a request.
+type Behavior struct {
+	SleepSeconds float64         `json:"sleep_seconds"`
+	Status       int             `json:"status"`
+	Body         json.RawMessage `json:"body,omitempty"`
+	RawBody      string          `json:"raw_body,omitempty"`
+	AsyncOnly    bool            `json:"async_only,omitempty"`
+}
+
+// FetchBehavior is how the broker responds to last_operation requests, until
+// and after an instance has been fetched max_fetch_service_instance_requests
+// times. When a Sequence is configured, the nth fetch of an instance is
+// instead answered with its nth behavior, repeating the last one.
+type FetchBehavior struct {
+	InProgress Behavior   `json:"in_progress"`
+	Finished   Behavior   `json:"finished"`
+	Sequence   []Behavior `json:"sequence,omitempty"`
+}
+
+type ServiceInstance struct {
+	ProvisionData map[string]interface{} `json:"provision_data"`
+	FetchCount    int                    `json:"fetch_count"`
+	Deleted       bool                   `json:"deleted"`
+}
+
+// initData makes sure an instance created from a "null" body, or posted
+// to /config without provision_data, can still be updated.
+func (instance *ServiceInstance) initData() {
+	if instance.ProvisionData == nil {
+		instance.ProvisionData = map[string]interface{}{}
+	}
+}
+
+func (instance *ServiceInstance) PlanId() string {
+	planId, _ := instance.ProvisionData["plan_id"].(string)
+	return planId
+}
+
+type ServiceBinding struct {
+	BindingData map[string]interface{} `json:"binding_data"`
+	InstanceId  string                 `json:"instance_id"`
+	FetchCount  int                    `json:"fetch_count"`
+	Deleted     bool                   `json:"deleted"`
+}
+
+func (binding *ServiceBinding) initData() {
+	if binding.BindingData == nil {
+		binding.BindingData = map[string]interface{}{}
+	}
+}
+
+func (binding *ServiceBinding) PlanId() string {
+	planId, _ := binding.BindingData["plan_id"].(string)
+	return planId
+}
+
+// Request is a request the broker received from the Cloud Controller.
+type Request struct {
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Query   string      `json:"query"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+}
+
+type Broker struct {
+	mutex         sync.Mutex
+	defaultConfig []byte
+
+	config    map[string]interface{}
+	instances map[string]*ServiceInstance
+	bindings  map[string]*ServiceBinding
+	requests  []Request
+}
+
+// New returns a broker configured with defaultConfig, which it returns to
+// when POSTed to /config/reset.
+func New(defaultConfig []byte) (*Broker, error) {
+	broker := &Broker{defaultConfig: defaultConfig}
+	if err := broker.reset(); err != nil {
+		return nil, err
+	}
+	return broker, nil
+}
+
+func (broker *Broker) reset() error {
+	broker.config = map[string]interface{}{}
+	broker.instances = map[string]*ServiceInstance{}
+	broker.bindings = map[string]*ServiceBinding{}
+	broker.requests = []Request{}
+	return broker.merge(broker.defaultConfig)
+}
+
+// merge applies a configuration in the same way as the Ruby broker: top-level
+// objects are merged one level deep, so posting a behavior for an endpoint
+// replaces every plan's behavior for that endpoint.
+func (broker *Broker) merge(config []byte) error {
+	var update map[string]json.RawMessage
+	if err := json.Unmarshal(config, &update); err != nil {
+		return err
+	}
+
+	for key, rawValue := range update {
+		switch key {
+		case "service_instances":
+			var instances map[string]*ServiceInstance
+			if err := json.Unmarshal(rawValue, &instances); err != nil {
+				return err
+			}
+			for id, instance := range instances {
+				instance.initData()
+				broker.instances[id] = instance
+			}
+		case "service_bindings":
+			var bindings map[string]*ServiceBinding
+			if err := json.Unmarshal(rawValue, &bindings); err != nil {
+				return err
+			}
+			for id, binding := range bindings {
+				binding.initData()
+				broker.bindings[id] = binding
+			}
+		default:
+			var value interface{}
+			if err := json.Unmarshal(rawValue, &value); err != nil {
+				return err
+			}
+
+			existing, existingIsObject := broker.config[key].(map[string]interface{})
+			updated, updatedIsObject := value.(map[string]interface{})
+			if existingIsObject && updatedIsObject {
+				for name, nested := range updated {
+					existing[name] = nested
+				}
+			} else {
+				broker.config[key] = value
+			}
+		}
+	}
+	return nil
+}
+
+func (broker *Broker) maxFetchServiceInstanceRequests() int {
+	if max, ok := broker.config["max_fetch_service_instance_requests"].(float64); ok {
+		return int(max)
+	}
+	return 1
+}
+
+// behaviorFor decodes the behavior configured for an endpoint into
+// behavior, falling back to the "default" plan's behavior when none is
+// configured for planId.
+func (broker *Broker) behaviorFor(endpoint, planId string, behavior interface{}) error {
+	behaviors, _ := broker.config["behaviors"].(map[string]interface{})
+	endpointBehaviors, ok := behaviors[endpoint].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Behavior object is missing key: %s (tried to lookup plan_id %s)", endpoint, planId)
+	}
+
+	var planBehavior interface{} = endpointBehaviors
+	if endpoint != "catalog" {
+		if planBehavior, ok = endpointBehaviors[planId]; !ok {
+			log.Printf("Could not find response for plan id: %s", planId)
+			if planBehavior, ok = endpointBehaviors["default"]; !ok {
+				return fmt.Errorf("Behavior for %s is missing response for plan_id %s and default response.", endpoint, planId)
+			}
+		}
+	}
+
+	encoded, err := json.Marshal(planBehavior)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, behavior)
+}
+
+func (broker *Broker) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		broker.respondWithError(res, req, err)
+		return
+	}
+	log.Printf("%s %s %s", req.Method, req.URL.Path, req.URL.RawQuery)
+	log.Printf("Request headers: %v", req.Header)
+	log.Printf("Request body: %s", body)
+
+	// Requests are handled one at a time, but the broker does not sleep
+	// while holding the lock, so that a slow endpoint does not hold up
+	// others, e.g. reconfiguring the broker. The lock is released even if
+	// handling a request panics.
+	behavior, err := func() (*Behavior, error) {
+		broker.mutex.Lock()
+		defer broker.mutex.Unlock()
+
+		if strings.HasPrefix(req.URL.Path, "/v2/") {
+			broker.requests = append(broker.requests, Request{
+				Method:  req.Method,
+				Path:    req.URL.Path,
+				Query:   req.URL.RawQuery,
+				Headers: req.Header,
+				Body:    string(body),
+			})
+		}
+		return broker.route(res, req, body)
+	}()
+
+	if err != nil {
+		broker.respondWithError(res, req, err)
+	} else if behavior != nil {
+		respond(res, *behavior)
+	}
+}
+
+// route either returns the behavior to respond with, or responds itself.
+func (broker *Broker) route(res http.ResponseWriter, req *http.Request, body []byte) (*Behavior, error) {
+	path := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	acceptsIncomplete := req.URL.Query().Get("accepts_incomplete") == "true"
+
+	switch {
+	case len(path) == 2 && path[0] == "v2" && path[1] == "catalog" && req.Method == "GET":
+		return broker.behaviorForRequest("catalog", "", false)
+
+	case len(path) == 3 && path[0] == "v2" && path[1] == "service_instances":
+		return broker.serviceInstance(req.Method, path[2], body, acceptsIncomplete)
+
+	case len(path) == 4 && path[0] == "v2" && path[1] == "service_instances" && path[3] == "last_operation" && req.Method == "GET":
+		return broker.lastOperation(path[2])
+
+	case len(path) == 5 && path[0] == "v2" && path[1] == "service_instances" && path[3] == "service_bindings":
+		return broker.serviceBinding(req.Method, path[2], path[4], body, acceptsIncomplete)
+
+	case len(path) == 6 && path[0] == "v2" && path[1] == "service_instances" && path[3] == "service_bindings" && path[5] == "last_operation" && req.Method == "GET":
+		return broker.bindingLastOperation(path[4])
+
+	case len(path) == 1 && path[0] == "config" && req.Method == "GET":
+		return nil, broker.respondWithConfig(res, false)
+
+	case len(path) == 1 && path[0] == "config" && req.Method == "POST":
+		if err := broker.merge(body); err != nil {
+			return nil, err
+		}
+		return nil, broker.respondWithConfig(res, false)
+
+	case len(path) == 2 && path[0] == "config" && path[1] == "all" && req.Method == "GET":
+		return nil, broker.respondWithConfig(res, true)
+
+	case len(path) == 2 && path[0] == "config" && path[1] == "requests" && req.Method == "GET":
+		return nil, respondWithJSON(res, broker.requests)
+
+	case len(path) == 2 && path[0] == "config" && path[1] == "reset" && req.Method == "POST":
+		if err := broker.reset(); err != nil {
+			return nil, err
+		}
+		return nil, broker.respondWithConfig(res, false)
+	}
+
+	http.NotFound(res, req)
+	return nil, nil
+}
+
+func (broker *Broker) serviceInstance(method, instanceId string, body []byte, acceptsIncomplete bool) (*Behavior, error) {
+	switch method {
+	case "PUT":
+		instance := &ServiceInstance{}
+		if err := json.Unmarshal(body, &instance.ProvisionData); err != nil {
+			return nil, err
+		}
+		instance.initData()
+		broker.instances[instanceId] = instance
+		return broker.behaviorForRequest("provision", instance.PlanId(), acceptsIncomplete)
+
+	case "GET":
+		instance, ok := broker.instances[instanceId]
+		if !ok || instance.Deleted {
+			return &Behavior{Status: http.StatusNotFound, Body: mustMarshal(map[string]string{})}, nil
+		}
+		behavior, err := broker.behaviorForRequest("get_instance", instance.PlanId(), true)
+		if err != nil {
+			return nil, err
+		}
+		return withParameters(behavior, instance.ProvisionData), nil
+
+	case "PATCH":
+		var update map[string]interface{}
+		if err := json.Unmarshal(body, &update); err != nil {
+			return nil, err
+		}
+		planId, _ := update["plan_id"].(string)
+
+		behavior, err := broker.behaviorForRequest("update", planId, acceptsIncomplete)
+		if err != nil {
+			return nil, err
+		}
+		if instance, ok := broker.instances[instanceId]; ok && (behavior.Status == 200 || behavior.Status == 202) {
+			instance.initData()
+			for key, value := range update {
+				instance.ProvisionData[key] = value
+			}
+			instance.FetchCount = 0
+		}
+		return behavior, nil
+
+	case "DELETE":
+		planId := ""
+		if instance, ok := broker.instances[instanceId]; ok {
+			instance.Deleted = true
+			instance.FetchCount = 0
+			planId = instance.PlanId()
+		}
+		return broker.behaviorForRequest("deprovision", planId, acceptsIncomplete)
+	}
+
+	return &Behavior{Status: http.StatusMethodNotAllowed}, nil
+}
+
+func (broker *Broker) lastOperation(instanceId string) (*Behavior, error) {
+	instance, ok := broker.instances[instanceId]
+	if !ok {
+		return &Behavior{
+			Status: 200,
+			Body:   mustMarshal(map[string]string{"state": "failed", "description": "Broker could not find service instance by the given id " + instanceId}),
+		}, nil
+	}
+
+	var fetchBehavior FetchBehavior
+	if err := broker.behaviorFor("fetch", instance.PlanId(), &fetchBehavior); err != nil {
+		return nil, err
+	}
+
+	instance.FetchCount++
+	return broker.fetchResponse(fetchBehavior, instance.FetchCount), nil
+}
+
+func (broker *Broker) bindingLastOperation(bindingId string) (*Behavior, error) {
+	binding, ok := broker.bindings[bindingId]
+	if !ok {
+		return &Behavior{
+			Status: 200,
+			Body:   mustMarshal(map[string]string{"state": "failed", "description": "Broker could not find service binding by the given id " + bindingId}),
+		}, nil
+	}
+
+	var fetchBehavior FetchBehavior
+	if err := broker.behaviorFor("fetch_binding", binding.PlanId(), &fetchBehavior); err != nil {
+		return nil, err
+	}
+
+	binding.FetchCount++
+	return broker.fetchResponse(fetchBehavior, binding.FetchCount), nil
+}
+
+func (broker *Broker) fetchResponse(fetchBehavior FetchBehavior, fetchCount int) *Behavior {
+	if sequence := fetchBehavior.Sequence; len(sequence) > 0 {
+		if fetchCount > len(sequence) {
+			return &sequence[len(sequence)-1]
+		}
+		return &sequence[fetchCount-1]
+	}
+	if fetchCount > broker.maxFetchServiceInstanceRequests() {
+		return &fetchBehavior.Finished
+	}
+	return &fetchBehavior.InProgress
+}
+
+func (broker *Broker) serviceBinding(method, instanceId, bindingId string, body []byte, acceptsIncomplete bool) (*Behavior, error) {
+	switch method {
+	case "PUT":
+		binding := &ServiceBinding{InstanceId: instanceId}
+		if err := json.Unmarshal(body, &binding.BindingData); err != nil {
+			return nil, err
+		}
+		binding.initData()
+		broker.bindings[bindingId] = binding
+		return broker.behaviorForRequest("bind", binding.PlanId(), acceptsIncomplete)
+
+	case "GET":
+		binding, ok := broker.bindings[bindingId]
+		if !ok {
+			return broker.behaviorForRequest("get_binding", "", true)
+		}
+		behavior, err := broker.behaviorForRequest("get_binding", binding.PlanId(), true)
+		if err != nil {
+			return nil, err
+		}
+		return withParameters(behavior, binding.BindingData), nil
+
+	case "DELETE":
+		planId := ""
+		if binding, ok := broker.bindings[bindingId]; ok {
+			binding.Deleted = true
+			binding.FetchCount = 0
+			planId = binding.PlanId()
+		}
+		return broker.behaviorForRequest("unbind", planId, acceptsIncomplete)
+	}
+
+	return &Behavior{Status: http.StatusMethodNotAllowed}, nil
+}
+
+// withParameters adds the parameters an instance or binding was created or
+// last updated with to a successful response, unless the response is
+// configured with parameters of its own.
+func withParameters(behavior *Behavior, data map[string]interface{}) *Behavior {
+	parameters, ok := data["parameters"]
+	if !ok || behavior.Status != http.StatusOK {
+		return behavior
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(behavior.Body, &body); err != nil || body == nil {
+		return behavior
+	}
+	if _, ok := body["parameters"]; ok {
+		return behavior
+	}
+
+	body["parameters"] = parameters
+	behavior.Body = mustMarshal(body)
+	return behavior
+}
+
+// behaviorForRequest returns the configured behavior, unless it is
+// async_only and the request does not accept an incomplete response, in
+// which case the broker sleeps and then rejects the request.
+func (broker *Broker) behaviorForRequest(endpoint, planId string, acceptsIncomplete bool) (*Behavior, error) {
+	var behavior Behavior
+	if err := broker.behaviorFor(endpoint, planId, &behavior); err != nil {
+		return nil, err
+	}
+
+	if behavior.AsyncOnly && !acceptsIncomplete {
+		return &Behavior{
+			SleepSeconds: behavior.SleepSeconds,
+			Status:       422,
+			Body: mustMarshal(map[string]string{
+				"error":       "AsyncRequired",
+				"description": "This service plan requires client support for asynchronous service operations.",
+			}),
+		}, nil
+	}
+	return &behavior, nil
+}
+
+// respond sleeps and then responds with the body, or with the raw body if
+// there is none.
+func respond(res http.ResponseWriter, behavior Behavior) {
+	time.Sleep(time.Duration(behavior.SleepSeconds * float64(time.Second)))
+
+	body := []byte(behavior.Body)
+	if len(body) == 0 || string(body) == "null" {
+		body = []byte(behavior.RawBody)
+	}
+
+	log.Printf("Response: status=%d, body=%s", behavior.Status, body)
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(behavior.Status)
+	res.Write(body)
+}
+
+func (broker *Broker) respondWithConfig(res http.ResponseWriter, includeInstancesAndBindings bool) error {
+	config := map[string]interface{}{}
+	for key, value := range broker.config {
+		config[key] = value
+	}
+	if includeInstancesAndBindings {
+		config["service_instances"] = broker.instances
+		config["service_bindings"] = broker.bindings
+	}
+	return respondWithJSON(res, config)
+}
+
+func respondWithJSON(res http.ResponseWriter, value interface{}) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	var indented bytes.Buffer
+	json.Indent(&indented, body, "", "  ")
+	res.Header().Set("Content-Type", "application/json")
+	_, err = indented.WriteTo(res)
+	return err
+}
+
+func (broker *Broker) respondWithError(res http.ResponseWriter, req *http.Request, err error) {
+	log.Printf("Error: %s", err)
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(res).Encode(map[string]interface{}{
+		"error":     true,
+		"message":   err.Error(),
+		"path":      req.URL.String(),
+		"timestamp": time.Now(),
+		"type":      "500",
+	})
+}
+
+func mustMarshal(value interface{}) json.RawMessage {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	return encoded
+}
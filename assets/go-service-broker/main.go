@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/cloudfoundry/cf-acceptance-tests/assets/go-service-broker/broker"
+)
+
+func main() {
+	defaultConfig, err := ioutil.ReadFile("data.json")
+	if err != nil {
+		panic(err)
+	}
+
+	serviceBroker, err := broker.New(defaultConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	fmt.Println("listening...")
+	err = http.ListenAndServe(":"+port, serviceBroker)
+	if err != nil {
+		panic(err)
+	}
+}
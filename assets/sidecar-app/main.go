@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// The sidecar writes its info to the container's filesystem and serves it on
+// the loopback interface, so that the web process can only find it if they
+// share a container and a network namespace.
+const (
+	sidecarInfoFile = "/tmp/sidecar-info.json"
+	sidecarAddress  = "127.0.0.1:8082"
+)
+
+type Info struct {
+	Id       string `json:"id"`
+	Hostname string `json:"hostname"`
+	Pid      int    `json:"pid"`
+}
+
+func main() {
+	info := newInfo()
+
+	if len(os.Args) > 1 && os.Args[1] == "sidecar" {
+		runSidecar(info)
+		return
+	}
+
+	http.HandleFunc("/", func(res http.ResponseWriter, req *http.Request) {
+		fmt.Fprintln(res, "sidecar app")
+	})
+	http.HandleFunc("/info", func(res http.ResponseWriter, req *http.Request) {
+		writeJSON(res, info)
+	})
+	http.HandleFunc("/sidecar", sidecar)
+	http.HandleFunc("/sidecar/file", sidecarFile)
+
+	fmt.Println("listening...")
+	err := http.ListenAndServe(":"+os.Getenv("PORT"), nil)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func runSidecar(info Info) {
+	contents, err := json.Marshal(info)
+	if err != nil {
+		panic(err)
+	}
+	err = ioutil.WriteFile(sidecarInfoFile, contents, 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	http.HandleFunc("/info", func(res http.ResponseWriter, req *http.Request) {
+		writeJSON(res, info)
+	})
+
+	fmt.Printf("sidecar %s listening on %s...\n", info.Id, sidecarAddress)
+	err = http.ListenAndServe(sidecarAddress, nil)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// sidecar returns the info served by the sidecar over the loopback interface.
+func sidecar(res http.ResponseWriter, req *http.Request) {
+	response, err := http.Get("http://" + sidecarAddress + "/info")
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer response.Body.Close()
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(response.StatusCode)
+	io.Copy(res, response.Body)
+}
+
+// sidecarFile returns the info the sidecar wrote to the filesystem.
+func sidecarFile(res http.ResponseWriter, req *http.Request) {
+	contents, err := ioutil.ReadFile(sidecarInfoFile)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(contents)
+}
+
+func newInfo() Info {
+	id := make([]byte, 8)
+	_, err := rand.Read(id)
+	if err != nil {
+		panic(err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		panic(err)
+	}
+
+	return Info{
+		Id:       hex.EncodeToString(id),
+		Hostname: hostname,
+		Pid:      os.Getpid(),
+	}
+}
+
+func writeJSON(res http.ResponseWriter, value interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(res).Encode(value)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const chunkSize = 1024
+
+func main() {
+	http.HandleFunc("/", hello)
+	http.HandleFunc("/chunked/", chunked)
+	http.HandleFunc("/sse/", serverSentEvents)
+	http.HandleFunc("/longpoll/", longPoll)
+	http.HandleFunc("/upload", upload)
+
+	fmt.Println("listening...")
+	err := http.ListenAndServe(":"+os.Getenv("PORT"), nil)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func hello(res http.ResponseWriter, req *http.Request) {
+	fmt.Fprintln(res, "streaming app")
+}
+
+// chunked writes :kbytes of random data, flushing after every kilobyte so
+// that the response is sent with chunked transfer encoding.
+func chunked(res http.ResponseWriter, req *http.Request) {
+	kbytes, err := intParam(req.URL.Path, "/chunked/")
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body := randomBytes(kbytes * chunkSize)
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		http.Error(res, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/octet-stream")
+	res.Header().Set("X-Sha256", checksum(body))
+	res.WriteHeader(http.StatusOK)
+
+	for offset := 0; offset < len(body); offset += chunkSize {
+		res.Write(body[offset : offset+chunkSize])
+		flusher.Flush()
+	}
+	fmt.Printf("Wrote %d chunks\n", kbytes)
+}
+
+// serverSentEvents emits :count events one second apart, followed by a
+// "checksum" event carrying the sha256 of all the event data sent.
+func serverSentEvents(res http.ResponseWriter, req *http.Request) {
+	count, err := intParam(req.URL.Path, "/sse/")
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		http.Error(res, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.WriteHeader(http.StatusOK)
+
+	digest := sha256.New()
+	for i := 0; i < count; i++ {
+		data := hex.EncodeToString(randomBytes(32))
+		digest.Write([]byte(data))
+
+		fmt.Fprintf(res, "id: %d\ndata: %s\n\n", i, data)
+		flusher.Flush()
+		time.Sleep(1 * time.Second)
+	}
+
+	fmt.Fprintf(res, "event: checksum\ndata: %s\n\n", hex.EncodeToString(digest.Sum(nil)))
+	flusher.Flush()
+	fmt.Printf("Streamed %d events\n", count)
+}
+
+// longPoll holds the request open for :seconds before answering with a
+// kilobyte of random data.
+func longPoll(res http.ResponseWriter, req *http.Request) {
+	seconds, err := intParam(req.URL.Path, "/longpoll/")
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	time.Sleep(time.Duration(seconds) * time.Second)
+
+	body := randomBytes(chunkSize)
+	res.Header().Set("Content-Type", "application/octet-stream")
+	res.Header().Set("X-Sha256", checksum(body))
+	res.Write(body)
+	fmt.Printf("Answered long poll after %d seconds\n", seconds)
+}
+
+// upload reports the size and sha256 of the request body it received.
+func upload(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" && req.Method != "PUT" {
+		http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	digest := sha256.New()
+	received, err := io.Copy(digest, req.Body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(res).Encode(map[string]interface{}{
+		"bytes":  received,
+		"sha256": hex.EncodeToString(digest.Sum(nil)),
+	})
+	fmt.Printf("Received upload of %d bytes\n", received)
+}
+
+func intParam(path, prefix string) (int, error) {
+	value, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(path, prefix), "/"))
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid parameter in path %s", path)
+	}
+	return value, nil
+}
+
+func randomBytes(n int) []byte {
+	bytes := make([]byte, n)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		panic(err)
+	}
+	return bytes
+}
+
+func checksum(bytes []byte) string {
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:])
+}
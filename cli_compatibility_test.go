@@ -7,7 +7,7 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-const minCliVersion = "6.16.1"
+const minCliVersion = "6.32.0"
 
 var _ = Describe("cf CLI version", func() {
 	It("meets the minimum required CLI version for the CATs", func() {
@@ -1,6 +1,7 @@
 package app_helpers
 
 import (
+	"encoding/json"
 	"strings"
 	"time"
 
@@ -54,3 +55,25 @@ func AppReport(appName string, timeout time.Duration) {
 	Eventually(cf.Cf("app", appName, "--guid"), timeout).Should(Exit())
 	Eventually(cf.Cf("logs", appName, "--recent"), timeout).Should(Exit())
 }
+
+// AppStats is an app's /v2/apps/:guid/stats, by instance index.
+type AppStats map[string]AppInstanceStats
+
+type AppInstanceStats struct {
+	State string `json:"state"`
+	Stats struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	} `json:"stats"`
+}
+
+// GetAppStats reads the stats of an app's instances through the v2 API, which
+// both DEA and Diego apps support.
+func GetAppStats(appGuid string) AppStats {
+	session := cf.Cf("curl", "/v2/apps/"+appGuid+"/stats")
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var stats AppStats
+	Expect(json.Unmarshal(session.Out.Contents(), &stats)).To(Succeed(), string(session.Out.Contents()))
+	return stats
+}
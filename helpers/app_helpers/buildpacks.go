@@ -0,0 +1,62 @@
+package app_helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/runner"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+// CreateStackBuildpack creates an admin buildpack that is only used for apps
+// on the given stack, which `cf create-buildpack` cannot do, and uploads its
+// bits. It must be called as an admin.
+func CreateStackBuildpack(buildpackName, stackName, buildpackArchivePath string, position int) string {
+	buildpackBody := fmt.Sprintf(`{"name":"%s","stack":"%s","position":%d,"enabled":true}`, buildpackName, stackName, position)
+	session := cf.Cf("curl", "/v2/buildpacks", "-X", "POST", "-d", buildpackBody)
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var buildpack struct {
+		Metadata struct {
+			Guid string `json:"guid"`
+		} `json:"metadata"`
+	}
+	json.Unmarshal(session.Out.Contents(), &buildpack)
+	Expect(buildpack.Metadata.Guid).NotTo(BeEmpty(), string(session.Out.Contents()))
+
+	UploadBuildpackBits(buildpack.Metadata.Guid, buildpackArchivePath)
+	return buildpack.Metadata.Guid
+}
+
+func UploadBuildpackBits(buildpackGuid, buildpackArchivePath string) {
+	config := helpers.LoadConfig()
+	token := strings.TrimSpace(string(runner.Run("bash", "-c", "cf oauth-token | grep bearer").Wait(DEFAULT_TIMEOUT).Out.Contents()))
+
+	uploadUrl := fmt.Sprintf("%s%s/v2/buildpacks/%s/bits", config.Protocol(), config.ApiEndpoint, buildpackGuid)
+	curl := runner.Curl(uploadUrl, "-f", "-X", "PUT", "-F", "buildpack=@"+buildpackArchivePath, "-H", "Authorization: "+token).Wait(DEFAULT_TIMEOUT)
+	Expect(curl).To(Exit(0), string(curl.Err.Contents()))
+}
+
+func DeleteBuildpack(buildpackGuid string) {
+	Expect(cf.Cf("curl", fmt.Sprintf("/v2/buildpacks/%s", buildpackGuid), "-X", "DELETE").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+}
+
+func GetStackGuid(stackName string) string {
+	session := cf.Cf("curl", fmt.Sprintf("/v2/stacks?q=name:%s", stackName))
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var stacks struct {
+		Resources []struct {
+			Metadata struct {
+				Guid string `json:"guid"`
+			} `json:"metadata"`
+		} `json:"resources"`
+	}
+	json.Unmarshal(session.Out.Contents(), &stacks)
+	Expect(stacks.Resources).To(HaveLen(1), "stack %s is not listed in /v2/stacks", stackName)
+	return stacks.Resources[0].Metadata.Guid
+}
@@ -0,0 +1,197 @@
+package app_helpers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/gomega"
+)
+
+// Manifest renders an application manifest for `cf push -f`. Attributes
+// left at their zero value are not rendered, so that the platform defaults
+// apply.
+type Manifest struct {
+	// Inherit is the path of a parent manifest.
+	Inherit string
+	// Shared holds the top-level attributes every application inherits.
+	// Its Name is ignored.
+	Shared       ManifestApplication
+	Applications []ManifestApplication
+}
+
+type ManifestApplication struct {
+	Name                    string
+	Path                    string
+	Buildpack               string
+	Stack                   string
+	Command                 string
+	Memory                  string
+	DiskQuota               string
+	Instances               int
+	HealthCheckType         string
+	HealthCheckHttpEndpoint string
+	Routes                  []string
+	NoRoute                 bool
+	Env                     map[string]string
+	Services                []string
+	// Processes are only honoured by the v3 apply_manifest endpoint.
+	Processes []ManifestProcess
+}
+
+type ManifestProcess struct {
+	Type                    string
+	Command                 string
+	Memory                  string
+	DiskQuota               string
+	Instances               int
+	HealthCheckType         string
+	HealthCheckHttpEndpoint string
+}
+
+func (manifest Manifest) String() string {
+	lines := []string{"---"}
+	if manifest.Inherit != "" {
+		lines = append(lines, attribute("inherit", manifest.Inherit))
+	}
+
+	shared := manifest.Shared
+	shared.Name = ""
+	lines = append(lines, shared.lines()...)
+
+	if len(manifest.Applications) > 0 {
+		lines = append(lines, "applications:")
+		for _, application := range manifest.Applications {
+			lines = append(lines, listItem(application.lines())...)
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (manifest Manifest) WriteFile(path string) {
+	Expect(ioutil.WriteFile(path, []byte(manifest.String()), 0644)).To(Succeed())
+}
+
+// WriteVarsFile writes a file for `cf push --vars-file`. String values are
+// quoted and any other values are rendered as is, so that e.g. an int
+// substitutes as a number.
+func WriteVarsFile(path string, vars map[string]interface{}) {
+	var names []string
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		value := vars[name]
+		if stringValue, ok := value.(string); ok {
+			lines = append(lines, attribute(name, stringValue))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %v", name, value))
+		}
+	}
+	Expect(ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)).To(Succeed())
+}
+
+func (application ManifestApplication) lines() []string {
+	var lines []string
+	if application.Name != "" {
+		lines = append(lines, attribute("name", application.Name))
+	}
+	if application.Path != "" {
+		lines = append(lines, attribute("path", application.Path))
+	}
+	if application.Buildpack != "" {
+		lines = append(lines, attribute("buildpack", application.Buildpack))
+	}
+	if application.Stack != "" {
+		lines = append(lines, attribute("stack", application.Stack))
+	}
+
+	lines = append(lines, processLines(application.Command, application.Memory, application.DiskQuota, application.Instances, application.HealthCheckType, application.HealthCheckHttpEndpoint)...)
+
+	if application.NoRoute {
+		lines = append(lines, "no-route: true")
+	}
+	if len(application.Routes) > 0 {
+		lines = append(lines, "routes:")
+		for _, route := range application.Routes {
+			lines = append(lines, listItem([]string{attribute("route", route)})...)
+		}
+	}
+
+	if len(application.Env) > 0 {
+		lines = append(lines, "env:")
+		var names []string
+		for name := range application.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			lines = append(lines, "  "+attribute(name, application.Env[name]))
+		}
+	}
+
+	if len(application.Services) > 0 {
+		lines = append(lines, "services:")
+		for _, service := range application.Services {
+			lines = append(lines, "- "+strconv.Quote(service))
+		}
+	}
+
+	if len(application.Processes) > 0 {
+		lines = append(lines, "processes:")
+		for _, process := range application.Processes {
+			processAttributes := []string{attribute("type", process.Type)}
+			processAttributes = append(processAttributes, processLines(process.Command, process.Memory, process.DiskQuota, process.Instances, process.HealthCheckType, process.HealthCheckHttpEndpoint)...)
+			lines = append(lines, listItem(processAttributes)...)
+		}
+	}
+
+	return lines
+}
+
+func processLines(command, memory, diskQuota string, instances int, healthCheckType, healthCheckHttpEndpoint string) []string {
+	var lines []string
+	if command != "" {
+		lines = append(lines, attribute("command", command))
+	}
+	if memory != "" {
+		lines = append(lines, attribute("memory", memory))
+	}
+	if diskQuota != "" {
+		lines = append(lines, attribute("disk_quota", diskQuota))
+	}
+	if instances != 0 {
+		lines = append(lines, fmt.Sprintf("instances: %d", instances))
+	}
+	if healthCheckType != "" {
+		lines = append(lines, attribute("health-check-type", healthCheckType))
+	}
+	if healthCheckHttpEndpoint != "" {
+		lines = append(lines, attribute("health-check-http-endpoint", healthCheckHttpEndpoint))
+	}
+	return lines
+}
+
+// attribute quotes the value, as YAML double-quoted strings share Go's
+// escaping.
+func attribute(name, value string) string {
+	return fmt.Sprintf("%s: %s", name, strconv.Quote(value))
+}
+
+func listItem(lines []string) []string {
+	item := make([]string, len(lines))
+	for i, line := range lines {
+		if i == 0 {
+			item[i] = "- " + line
+		} else {
+			item[i] = "  " + line
+		}
+	}
+	return item
+}
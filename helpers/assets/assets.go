@@ -6,6 +6,7 @@ type Assets struct {
 	DoraZip                  string
 	Fuse                     string
 	Golang                   string
+	GoServiceBroker          string
 	HelloWorld               string
 	HelloRouting             string
 	Java                     string
@@ -19,9 +20,12 @@ type Assets struct {
 	RubySimple               string
 	SecurityGroupBuildpack   string
 	ServiceBroker            string
+	SidecarApp               string
 	Staticfile               string
+	StreamingApp             string
 	SyslogDrainListener      string
 	Binary                   string
+	ConfigurableRouteService string
 	LoggingRouteService      string
 	WorkerApp                string
 	LatticeApp               string
@@ -34,24 +38,28 @@ func NewAssets() Assets {
 		DoraZip:                  "../assets/dora.zip",
 		Fuse:                     "../assets/fuse-mount",
 		Golang:                   "../assets/golang",
+		GoServiceBroker:          "../assets/go-service-broker",
 		HelloRouting:             "../assets/hello-routing",
 		HelloWorld:               "../assets/hello-world",
 		Java:                     "../assets/java",
 		JavaSpringZip:            "../assets/java-spring/java-spring.jar",
 		JavaUnwriteableZip:       "../assets/java-unwriteable-dir/java-unwriteable-dir.jar",
 		LoggregatorLoadGenerator: "../assets/loggregator-load-generator",
-		Node:                   "../assets/node",
-		NodeWithProcfile:       "../assets/node-with-procfile",
-		Php:                    "../assets/php",
-		Python:                 "../assets/python",
-		RubySimple:             "../assets/ruby_simple",
-		SecurityGroupBuildpack: "../assets/security_group_buildpack.zip",
-		ServiceBroker:          "../assets/service_broker",
-		Staticfile:             "../assets/staticfile",
-		SyslogDrainListener:    "../assets/syslog-drain-listener",
-		Binary:                 "../assets/binary",
-		LoggingRouteService:    "../assets/logging-route-service",
-		WorkerApp:              "../assets/worker-app",
-		LatticeApp:             "../assets/lattice-app",
+		Node:                     "../assets/node",
+		NodeWithProcfile:         "../assets/node-with-procfile",
+		Php:                      "../assets/php",
+		Python:                   "../assets/python",
+		RubySimple:               "../assets/ruby_simple",
+		SecurityGroupBuildpack:   "../assets/security_group_buildpack.zip",
+		ServiceBroker:            "../assets/service_broker",
+		SidecarApp:               "../assets/sidecar-app",
+		Staticfile:               "../assets/staticfile",
+		StreamingApp:             "../assets/streaming-app",
+		SyslogDrainListener:      "../assets/syslog-drain-listener",
+		Binary:                   "../assets/binary",
+		ConfigurableRouteService: "../assets/configurable-route-service",
+		LoggingRouteService:      "../assets/logging-route-service",
+		WorkerApp:                "../assets/worker-app",
+		LatticeApp:               "../assets/lattice-app",
 	}
 }
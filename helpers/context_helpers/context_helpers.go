@@ -0,0 +1,52 @@
+package context_helpers
+
+import (
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+// SecondaryContext is a ConfiguredContext with its own org, space and
+// regular user, for specs that need to act across organizations alongside
+// the suite's own context.
+type SecondaryContext struct {
+	*helpers.ConfiguredContext
+}
+
+func NewSecondaryContext(config helpers.Config) *SecondaryContext {
+	// An existing user is shared with the suite's own context, so it has to
+	// outlive this one.
+	if config.UseExistingUser {
+		config.ShouldKeepUser = true
+	}
+
+	return &SecondaryContext{helpers.NewContext(config)}
+}
+
+// Setup creates the org, quota and user, then gives the regular user a
+// space in the new org, as helpers.Environment does for the suite context.
+func (context *SecondaryContext) Setup() {
+	context.ConfiguredContext.Setup()
+
+	cf.AsUser(context.AdminUserContext(), context.ShortTimeout(), func() {
+		SetUpSpaceWithUserAccess(context, context.RegularUserContext().Space)
+	})
+}
+
+// SetUpSpaceWithUserAccess creates a space in the regular user's org and
+// gives the regular user the roles helpers.Environment gives it in its own
+// space. It must be called as an admin.
+func SetUpSpaceWithUserAccess(context helpers.SuiteContext, spaceName string) {
+	userContext := context.RegularUserContext()
+	Eventually(cf.Cf("create-space", "-o", userContext.Org, spaceName), context.ShortTimeout()).Should(Exit(0))
+	for _, role := range []string{"SpaceManager", "SpaceDeveloper", "SpaceAuditor"} {
+		Eventually(cf.Cf("set-space-role", userContext.Username, userContext.Org, spaceName, role), context.ShortTimeout()).Should(Exit(0))
+	}
+}
+
+// DeleteSpace deletes a space in the regular user's org, and everything in
+// it. It must be called as an admin.
+func DeleteSpace(context helpers.SuiteContext, spaceName string) {
+	Eventually(cf.Cf("delete-space", "-o", context.RegularUserContext().Org, spaceName, "-f"), context.LongTimeout()).Should(Exit(0))
+}
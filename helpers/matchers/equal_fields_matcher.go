@@ -0,0 +1,142 @@
+package matchers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/onsi/gomega/types"
+)
+
+// EqualFields succeeds if actual has the same type as expected and all of
+// their exported fields are equal. Unlike Equal, its failure message lists
+// every mismatching field by its path, e.g. Routes[1].Path.
+func EqualFields(expected interface{}) types.GomegaMatcher {
+	return &EqualFieldsMatcher{
+		expected: expected,
+	}
+}
+
+type EqualFieldsMatcher struct {
+	expected interface{}
+}
+
+func (matcher *EqualFieldsMatcher) Match(actual interface{}) (success bool, err error) {
+	if reflect.TypeOf(actual) != reflect.TypeOf(matcher.expected) {
+		return false, fmt.Errorf("EqualFieldsMatcher matcher: actual value must be a %T, got a %T", matcher.expected, actual)
+	}
+
+	return len(FieldDiffs(matcher.expected, actual)) == 0, nil
+}
+
+func (matcher *EqualFieldsMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected fields to be equal, but:\n\t%s", strings.Join(FieldDiffs(matcher.expected, actual), "\n\t"))
+}
+
+func (matcher *EqualFieldsMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n\t%#v\nnot to have the same fields as\n\t%#v", actual, matcher.expected)
+}
+
+// FieldDiffs describes each exported field, slice element and map entry
+// that differs between two values of the same type.
+func FieldDiffs(expected, actual interface{}) []string {
+	return diffValues("", reflect.ValueOf(expected), reflect.ValueOf(actual))
+}
+
+func diffValues(path string, expected, actual reflect.Value) []string {
+	var diffs []string
+	if !expected.IsValid() {
+		return diffs
+	}
+
+	switch expected.Kind() {
+	case reflect.Struct:
+		for i := 0; i < expected.NumField(); i++ {
+			field := expected.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			diffs = append(diffs, diffValues(fieldPath(path, field.Name), expected.Field(i), actual.Field(i))...)
+		}
+
+	case reflect.Slice, reflect.Array:
+		if expected.Len() != actual.Len() {
+			return []string{fmt.Sprintf("%s: expected %d elements %#v, got %d elements %#v",
+				valuePath(path), expected.Len(), expected.Interface(), actual.Len(), actual.Interface())}
+		}
+		for i := 0; i < expected.Len(); i++ {
+			diffs = append(diffs, diffValues(fmt.Sprintf("%s[%d]", path, i), expected.Index(i), actual.Index(i))...)
+		}
+
+	case reflect.Map:
+		for _, key := range sortedMapKeys(expected, actual) {
+			keyPath := fmt.Sprintf("%s[%#v]", path, key.Interface())
+			expectedValue, actualValue := expected.MapIndex(key), actual.MapIndex(key)
+			switch {
+			case !actualValue.IsValid():
+				diffs = append(diffs, fmt.Sprintf("%s: expected %#v, got nothing", keyPath, expectedValue.Interface()))
+			case !expectedValue.IsValid():
+				diffs = append(diffs, fmt.Sprintf("%s: expected nothing, got %#v", keyPath, actualValue.Interface()))
+			default:
+				diffs = append(diffs, diffValues(keyPath, expectedValue, actualValue)...)
+			}
+		}
+
+	case reflect.Ptr, reflect.Interface:
+		if expected.IsNil() || actual.IsNil() {
+			if expected.IsNil() != actual.IsNil() {
+				diffs = append(diffs, fmt.Sprintf("%s: expected %#v, got %#v", valuePath(path), expected.Interface(), actual.Interface()))
+			}
+			return diffs
+		}
+		if expected.Elem().Type() != actual.Elem().Type() {
+			return []string{fmt.Sprintf("%s: expected a %s, got a %s", valuePath(path), expected.Elem().Type(), actual.Elem().Type())}
+		}
+		return diffValues(path, expected.Elem(), actual.Elem())
+
+	default:
+		if !reflect.DeepEqual(expected.Interface(), actual.Interface()) {
+			diffs = append(diffs, fmt.Sprintf("%s: expected %#v, got %#v", valuePath(path), expected.Interface(), actual.Interface()))
+		}
+	}
+
+	return diffs
+}
+
+// sortedMapKeys returns the keys of both maps, ordered by their printed
+// value so that diffs are reported in a stable order.
+func sortedMapKeys(expected, actual reflect.Value) []reflect.Value {
+	keysByName := map[string]reflect.Value{}
+	for _, mapValue := range []reflect.Value{expected, actual} {
+		for _, key := range mapValue.MapKeys() {
+			keysByName[fmt.Sprintf("%#v", key.Interface())] = key
+		}
+	}
+
+	var names []string
+	for name := range keysByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keys := make([]reflect.Value, len(names))
+	for i, name := range names {
+		keys[i] = keysByName[name]
+	}
+	return keys
+}
+
+func fieldPath(path, fieldName string) string {
+	if path == "" {
+		return fieldName
+	}
+	return path + "." + fieldName
+}
+
+func valuePath(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return path
+}
@@ -0,0 +1,72 @@
+package matchers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/v3_helpers"
+	"github.com/onsi/gomega/types"
+)
+
+// HaveInstancesInState succeeds if exactly count of the instances in a
+// v3_helpers.ProcessStats or an app_helpers.AppStats are in the given state.
+func HaveInstancesInState(count int, state string) types.GomegaMatcher {
+	return &InstancesInStateMatcher{
+		count: count,
+		state: state,
+	}
+}
+
+type InstancesInStateMatcher struct {
+	count int
+	state string
+}
+
+func (matcher *InstancesInStateMatcher) Match(actual interface{}) (success bool, err error) {
+	states, ok := instanceStatesByIndex(actual)
+	if !ok {
+		return false, fmt.Errorf("InstancesInStateMatcher matcher: actual value must be a v3_helpers.ProcessStats or an app_helpers.AppStats")
+	}
+
+	count := 0
+	for _, state := range states {
+		if state == matcher.state {
+			count++
+		}
+	}
+	return count == matcher.count, nil
+}
+
+func (matcher *InstancesInStateMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n\t%s\nto have %d instances in state %s", instanceStates(actual), matcher.count, matcher.state)
+}
+
+func (matcher *InstancesInStateMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n\t%s\nnot to have %d instances in state %s", instanceStates(actual), matcher.count, matcher.state)
+}
+
+func instanceStatesByIndex(actual interface{}) (map[string]string, bool) {
+	states := map[string]string{}
+	switch stats := actual.(type) {
+	case v3_helpers.ProcessStats:
+		for _, instance := range stats.Instances {
+			states[strconv.Itoa(instance.Index)] = instance.State
+		}
+	case app_helpers.AppStats:
+		for index, instance := range stats {
+			states[index] = instance.State
+		}
+	default:
+		return nil, false
+	}
+	return states, true
+}
+
+func instanceStates(actual interface{}) string {
+	states, ok := instanceStatesByIndex(actual)
+	if !ok {
+		return fmt.Sprintf("%#v", actual)
+	}
+	return fmt.Sprintf("instances by index %v", states)
+}
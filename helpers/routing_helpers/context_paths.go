@@ -0,0 +1,49 @@
+package routing_helpers
+
+import (
+	"time"
+
+	. "github.com/cloudfoundry-incubator/cf-routing-test-helpers/helpers"
+)
+
+// ContextPathApps is an app on the root route of a hostname, together with
+// an app on each of a number of context paths of the same hostname.
+type ContextPathApps struct {
+	Hostname string
+	RootApp  string
+	// PathApps holds the app on each context path, by path.
+	PathApps map[string]string
+}
+
+// PushContextPathApps pushes the root app, whose name is also the hostname,
+// and an app for each path, mapping each to the hostname on its path.
+func PushContextPathApps(asset, buildpackName, domain string, paths []string, pushTimeout, timeout time.Duration) ContextPathApps {
+	apps := ContextPathApps{
+		RootApp:  GenerateAppName(),
+		PathApps: map[string]string{},
+	}
+	apps.Hostname = apps.RootApp
+	PushApp(apps.RootApp, asset, buildpackName, domain, pushTimeout)
+
+	for _, path := range paths {
+		app := GenerateAppName()
+		PushApp(app, asset, buildpackName, domain, pushTimeout)
+		MapRouteToApp(app, domain, apps.Hostname, path, timeout)
+		apps.PathApps[path] = app
+	}
+	return apps
+}
+
+func (apps ContextPathApps) Report(timeout time.Duration) {
+	AppReport(apps.RootApp, timeout)
+	for _, app := range apps.PathApps {
+		AppReport(app, timeout)
+	}
+}
+
+func (apps ContextPathApps) Delete(timeout time.Duration) {
+	DeleteApp(apps.RootApp, timeout)
+	for _, app := range apps.PathApps {
+		DeleteApp(app, timeout)
+	}
+}
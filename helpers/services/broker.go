@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 
 	. "github.com/onsi/gomega"
@@ -16,7 +18,6 @@ import (
 	"github.com/cloudfoundry-incubator/cf-test-helpers/runner"
 
 	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
-	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
 )
 
 type Plan struct {
@@ -25,8 +26,11 @@ type Plan struct {
 }
 
 type ServiceBroker struct {
-	Name    string
-	Path    string
+	Name string
+	Path string
+	// Url is where the broker is served when it is not pushed as an app,
+	// e.g. by an httptest server.
+	Url     string
 	context helpers.SuiteContext
 	Service struct {
 		Name            string `json:"name"`
@@ -81,6 +85,12 @@ type SpaceJson struct {
 	}
 }
 
+// CloudControllerError is the body of a Cloud Controller v2 API error.
+type CloudControllerError struct {
+	ErrorCode   string `json:"error_code"`
+	Description string `json:"description"`
+}
+
 func NewServiceBroker(name string, path string, context helpers.SuiteContext) ServiceBroker {
 	b := ServiceBroker{}
 	b.Path = path
@@ -104,10 +114,15 @@ func NewServiceBroker(name string, path string, context helpers.SuiteContext) Se
 
 func (b ServiceBroker) Push() {
 	config := helpers.LoadConfig()
+	buildpackName := config.RubyBuildpackName
+	if _, err := os.Stat(filepath.Join(b.Path, "Godeps", "Godeps.json")); err == nil {
+		buildpackName = config.GoBuildpackName
+	}
+
 	Expect(cf.Cf(
 		"push", b.Name,
 		"--no-start",
-		"-b", config.RubyBuildpackName,
+		"-b", buildpackName,
 		"-m", DEFAULT_MEMORY_LIMIT,
 		"-p", b.Path,
 		"-d", config.AppsDomain,
@@ -117,7 +132,14 @@ func (b ServiceBroker) Push() {
 }
 
 func (b ServiceBroker) Configure() {
-	Expect(runner.Curl(helpers.AppUri(b.Name, "/config"), "-d", b.ToJSON()).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+	Expect(runner.Curl(b.uri("/config"), "-d", b.ToJSON()).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+}
+
+func (b ServiceBroker) uri(endpoint string) string {
+	if b.Url != "" {
+		return b.Url + endpoint
+	}
+	return helpers.AppUri(b.Name, endpoint)
 }
 
 func (b ServiceBroker) Restart() {
@@ -126,21 +148,21 @@ func (b ServiceBroker) Restart() {
 
 func (b ServiceBroker) Create() {
 	cf.AsUser(b.context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
-		Expect(cf.Cf("create-service-broker", b.Name, "username", "password", helpers.AppUri(b.Name, "")).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
-		Expect(cf.Cf("service-brokers").Wait(DEFAULT_TIMEOUT)).To(Say(b.Name))
+		Expect(cf.Cf("create-service-broker", b.Name, "username", "password", b.uri("")).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("service-brokers").Wait(DEFAULT_TIMEOUT)).To(Say("%s", b.Name))
 	})
 }
 
 func (b ServiceBroker) CreateSpaceScoped() {
 	cf.AsUser(b.context.RegularUserContext(), DEFAULT_TIMEOUT, func() {
-		Expect(cf.Cf("create-service-broker", b.Name, "username", "password", helpers.AppUri(b.Name, ""), "--space-scoped").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
-		Expect(cf.Cf("service-brokers").Wait(DEFAULT_TIMEOUT)).To(Say(b.Name))
+		Expect(cf.Cf("create-service-broker", b.Name, "username", "password", b.uri(""), "--space-scoped").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("service-brokers").Wait(DEFAULT_TIMEOUT)).To(Say("%s", b.Name))
 	})
 }
 
 func (b ServiceBroker) Update() {
 	cf.AsUser(b.context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
-		Expect(cf.Cf("update-service-broker", b.Name, "username", "password", helpers.AppUri(b.Name, "")).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("update-service-broker", b.Name, "username", "password", b.uri("")).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
 	})
 }
 
@@ -163,7 +185,7 @@ func (b ServiceBroker) Destroy() {
 }
 
 func (b ServiceBroker) ToJSON() string {
-	bytes, err := ioutil.ReadFile(assets.NewAssets().ServiceBroker + "/cats.json")
+	bytes, err := ioutil.ReadFile(b.Path + "/cats.json")
 	Expect(err).To(BeNil())
 
 	replacer := strings.NewReplacer(
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/runner"
+)
+
+// BrokerResponse is how the test broker responds to a request to one of its
+// endpoints.
+type BrokerResponse struct {
+	SleepSeconds float64     `json:"sleep_seconds"`
+	Status       int         `json:"status"`
+	Body         interface{} `json:"body,omitempty"`
+	RawBody      string      `json:"raw_body,omitempty"`
+	AsyncOnly    bool        `json:"async_only,omitempty"`
+}
+
+// LastOperationState is a response to a last_operation request reporting
+// state, e.g. "in progress", "succeeded" or "failed".
+func LastOperationState(state, description string) BrokerResponse {
+	return BrokerResponse{
+		Status: 200,
+		Body:   map[string]string{"state": state, "description": description},
+	}
+}
+
+// LastOperationResponses is how the test broker responds to last_operation
+// requests, until and after an instance or binding has been fetched
+// MaxFetchServiceInstanceRequests times.
+type LastOperationResponses struct {
+	InProgress BrokerResponse   `json:"in_progress"`
+	Finished   BrokerResponse   `json:"finished"`
+	Sequence   []BrokerResponse `json:"sequence,omitempty"`
+}
+
+// BrokerBehaviors are the test broker's responses to each of its endpoints,
+// keyed by plan id, except for the catalog's. Only the Go broker in
+// assets/go-service-broker serves instances and bindings, or binds
+// asynchronously.
+type BrokerBehaviors struct {
+	Catalog      BrokerResponse                    `json:"catalog"`
+	Provision    map[string]BrokerResponse         `json:"provision"`
+	Fetch        map[string]LastOperationResponses `json:"fetch"`
+	Update       map[string]BrokerResponse         `json:"update"`
+	Deprovision  map[string]BrokerResponse         `json:"deprovision"`
+	GetInstance  map[string]BrokerResponse         `json:"get_instance,omitempty"`
+	Bind         map[string]BrokerResponse         `json:"bind"`
+	Unbind       map[string]BrokerResponse         `json:"unbind"`
+	FetchBinding map[string]LastOperationResponses `json:"fetch_binding,omitempty"`
+	GetBinding   map[string]BrokerResponse         `json:"get_binding,omitempty"`
+}
+
+// BrokerConfig is the test broker's configuration.
+type BrokerConfig struct {
+	Behaviors                       BrokerBehaviors `json:"behaviors"`
+	MaxFetchServiceInstanceRequests int             `json:"max_fetch_service_instance_requests"`
+}
+
+// BrokerState is the test broker's configuration, together with the service
+// instances and bindings it was asked to create.
+type BrokerState struct {
+	BrokerConfig
+	ServiceInstances map[string]BrokerServiceInstance `json:"service_instances"`
+	ServiceBindings  map[string]BrokerServiceBinding  `json:"service_bindings"`
+}
+
+type BrokerServiceInstance struct {
+	ProvisionData map[string]interface{} `json:"provision_data"`
+	FetchCount    int                    `json:"fetch_count"`
+	Deleted       bool                   `json:"deleted"`
+}
+
+type BrokerServiceBinding struct {
+	BindingData map[string]interface{} `json:"binding_data"`
+	InstanceId  string                 `json:"instance_id"`
+	FetchCount  int                    `json:"fetch_count"`
+	Deleted     bool                   `json:"deleted"`
+}
+
+// BrokerRequest is a request the test broker received on one of its /v2
+// endpoints.
+type BrokerRequest struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Query   string              `json:"query"`
+	Headers map[string][]string `json:"headers"`
+	Body    string              `json:"body"`
+}
+
+// Header returns the first value of the named header, whatever its case.
+func (request BrokerRequest) Header(name string) string {
+	return http.Header(request.Headers).Get(name)
+}
+
+func (request BrokerRequest) QueryValues() url.Values {
+	values, err := url.ParseQuery(request.Query)
+	Expect(err).NotTo(HaveOccurred())
+	return values
+}
+
+func (request BrokerRequest) JSONBody() map[string]interface{} {
+	var body map[string]interface{}
+	Expect(json.Unmarshal([]byte(request.Body), &body)).To(Succeed(), request.Body)
+	return body
+}
+
+// EndpointBehavior configures how the broker responds to requests to an
+// endpoint for a plan. Every change is sent to the broker straight away.
+type EndpointBehavior struct {
+	broker    ServiceBroker
+	endpoint  string
+	responses func(*BrokerBehaviors) *map[string]BrokerResponse
+	planId    string
+	response  BrokerResponse
+}
+
+func (b ServiceBroker) OnProvision(planId string) *EndpointBehavior {
+	return b.on("provision", planId, func(behaviors *BrokerBehaviors) *map[string]BrokerResponse {
+		return &behaviors.Provision
+	})
+}
+
+// OnGetInstance configures the response to requests for an instance, which
+// the Cloud Controller makes to show its parameters. Only the Go broker in
+// assets/go-service-broker serves instances.
+func (b ServiceBroker) OnGetInstance(planId string) *EndpointBehavior {
+	return b.on("get_instance", planId, func(behaviors *BrokerBehaviors) *map[string]BrokerResponse {
+		return &behaviors.GetInstance
+	})
+}
+
+func (b ServiceBroker) OnUpdate(planId string) *EndpointBehavior {
+	return b.on("update", planId, func(behaviors *BrokerBehaviors) *map[string]BrokerResponse {
+		return &behaviors.Update
+	})
+}
+
+func (b ServiceBroker) OnDeprovision(planId string) *EndpointBehavior {
+	return b.on("deprovision", planId, func(behaviors *BrokerBehaviors) *map[string]BrokerResponse {
+		return &behaviors.Deprovision
+	})
+}
+
+func (b ServiceBroker) OnBind(planId string) *EndpointBehavior {
+	return b.on("bind", planId, func(behaviors *BrokerBehaviors) *map[string]BrokerResponse {
+		return &behaviors.Bind
+	})
+}
+
+func (b ServiceBroker) OnUnbind(planId string) *EndpointBehavior {
+	return b.on("unbind", planId, func(behaviors *BrokerBehaviors) *map[string]BrokerResponse {
+		return &behaviors.Unbind
+	})
+}
+
+// OnGetBinding configures the response to requests for a binding, which the
+// Cloud Controller makes once an asynchronous bind has succeeded.
+func (b ServiceBroker) OnGetBinding(planId string) *EndpointBehavior {
+	return b.on("get_binding", planId, func(behaviors *BrokerBehaviors) *map[string]BrokerResponse {
+		return &behaviors.GetBinding
+	})
+}
+
+// on starts from the plan's current behavior, or the default plan's if it
+// has none of its own. responses picks the endpoint's behaviors out of the
+// broker's.
+func (b ServiceBroker) on(endpoint, planId string, responses func(*BrokerBehaviors) *map[string]BrokerResponse) *EndpointBehavior {
+	behavior := &EndpointBehavior{broker: b, endpoint: endpoint, responses: responses, planId: planId}
+
+	config := b.GetConfig()
+	planResponses := *responses(&config.Behaviors)
+	current, ok := planResponses[planId]
+	if !ok {
+		current = planResponses["default"]
+	}
+	behavior.response = current
+	return behavior
+}
+
+func (behavior *EndpointBehavior) RespondWith(status int, body interface{}) *EndpointBehavior {
+	behavior.response.Status = status
+	behavior.response.Body = body
+	behavior.response.RawBody = ""
+	return behavior.configure()
+}
+
+// RespondWithRawBody responds with a body that need not be valid JSON.
+func (behavior *EndpointBehavior) RespondWithRawBody(status int, rawBody string) *EndpointBehavior {
+	behavior.response.Status = status
+	behavior.response.Body = nil
+	behavior.response.RawBody = rawBody
+	return behavior.configure()
+}
+
+// After delays every response by delay.
+func (behavior *EndpointBehavior) After(delay time.Duration) *EndpointBehavior {
+	behavior.response.SleepSeconds = delay.Seconds()
+	return behavior.configure()
+}
+
+// AsyncOnly rejects requests that do not accept an incomplete response with
+// a 422 AsyncRequired.
+func (behavior *EndpointBehavior) AsyncOnly() *EndpointBehavior {
+	behavior.response.AsyncOnly = true
+	return behavior.configure()
+}
+
+func (behavior *EndpointBehavior) configure() *EndpointBehavior {
+	config := behavior.broker.GetConfig()
+	planResponses := *behavior.responses(&config.Behaviors)
+	if planResponses == nil {
+		planResponses = map[string]BrokerResponse{}
+	}
+	planResponses[behavior.planId] = behavior.response
+
+	behavior.broker.configureBehavior(behavior.endpoint, planResponses)
+	return behavior
+}
+
+// LastOperationBehavior configures how the broker responds to last_operation
+// requests for instances or bindings of a plan.
+type LastOperationBehavior struct {
+	broker    ServiceBroker
+	endpoint  string
+	responses func(*BrokerBehaviors) *map[string]LastOperationResponses
+	planId    string
+}
+
+func (b ServiceBroker) OnLastOperation(planId string) *LastOperationBehavior {
+	return &LastOperationBehavior{
+		broker:   b,
+		endpoint: "fetch",
+		responses: func(behaviors *BrokerBehaviors) *map[string]LastOperationResponses {
+			return &behaviors.Fetch
+		},
+		planId: planId,
+	}
+}
+
+// OnBindingLastOperation is only supported by the Go broker in
+// assets/go-service-broker.
+func (b ServiceBroker) OnBindingLastOperation(planId string) *LastOperationBehavior {
+	return &LastOperationBehavior{
+		broker:   b,
+		endpoint: "fetch_binding",
+		responses: func(behaviors *BrokerBehaviors) *map[string]LastOperationResponses {
+			return &behaviors.FetchBinding
+		},
+		planId: planId,
+	}
+}
+
+// Sequence answers the nth last_operation request for an instance or binding
+// with the nth response, and every later request with the last one. Sequences are only
+// supported by the Go broker in assets/go-service-broker.
+func (behavior *LastOperationBehavior) Sequence(responses ...BrokerResponse) *LastOperationBehavior {
+	config := behavior.broker.GetConfig()
+	planResponses := *behavior.responses(&config.Behaviors)
+	if planResponses == nil {
+		planResponses = map[string]LastOperationResponses{}
+	}
+	planResponses[behavior.planId] = LastOperationResponses{Sequence: responses}
+
+	behavior.broker.configureBehavior(behavior.endpoint, planResponses)
+	return behavior
+}
+
+// GetCatalog returns the catalog the broker serves, for specs to change and
+// serve with ServeCatalog.
+func (b ServiceBroker) GetCatalog() map[string]interface{} {
+	catalog, ok := b.GetConfig().Behaviors.Catalog.Body.(map[string]interface{})
+	Expect(ok).To(BeTrue(), "the broker does not serve a catalog object")
+	return catalog
+}
+
+// ServeCatalog serves catalog from the broker's catalog endpoint, whether or
+// not it is valid, until the broker is configured again.
+func (b ServiceBroker) ServeCatalog(catalog interface{}) {
+	update, err := json.Marshal(map[string]interface{}{
+		"behaviors": map[string]interface{}{
+			"catalog": BrokerResponse{Status: 200, Body: catalog},
+		},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(runner.Curl(b.uri("/config"), "-d", string(update)).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+}
+
+// configureBehavior posts every plan's behavior for an endpoint. The broker
+// replaces every plan's behavior for an endpoint that is posted to /config,
+// so callers change a single plan's behavior alongside the others' current
+// ones.
+func (b ServiceBroker) configureBehavior(endpoint string, planBehaviors interface{}) {
+	update, err := json.Marshal(map[string]interface{}{
+		"behaviors": map[string]interface{}{endpoint: planBehaviors},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(runner.Curl(b.uri("/config"), "-d", string(update)).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+}
+
+func (b ServiceBroker) GetConfig() BrokerConfig {
+	var config BrokerConfig
+	b.getJSON("/config", &config)
+	return config
+}
+
+func (b ServiceBroker) GetState() BrokerState {
+	var state BrokerState
+	b.getJSON("/config/all", &state)
+	return state
+}
+
+// GetRequests lists every request the broker received on its /v2 endpoints
+// since it was last reset. Only the Go broker records requests.
+func (b ServiceBroker) GetRequests() []BrokerRequest {
+	var requests []BrokerRequest
+	b.getJSON("/config/requests", &requests)
+	return requests
+}
+
+// GetRequestsTo lists the requests the broker received with method to path,
+// e.g. "PUT" to "/v2/service_instances/<guid>".
+func (b ServiceBroker) GetRequestsTo(method, path string) []BrokerRequest {
+	requests := []BrokerRequest{}
+	for _, request := range b.GetRequests() {
+		if request.Method == method && request.Path == path {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+func (b ServiceBroker) getJSON(endpoint string, response interface{}) {
+	curl := runner.Curl(b.uri(endpoint)).Wait(DEFAULT_TIMEOUT)
+	Expect(curl).To(Exit(0))
+	Expect(json.Unmarshal(curl.Out.Contents(), response)).To(Succeed(), string(curl.Out.Contents()))
+}
@@ -0,0 +1,178 @@
+package services_test
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/services"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ServiceBroker behaviors", func() {
+	BeforeEach(func() {
+		serviceBroker.Configure()
+	})
+
+	Describe("OnProvision", func() {
+		It("changes only the given plan's response", func() {
+			serviceBroker.OnProvision(serviceBroker.SyncPlans[0].ID).RespondWith(http.StatusConflict, map[string]string{})
+
+			status, _ := provision("instance-guid", serviceBroker.SyncPlans[0], false)
+			Expect(status).To(Equal(http.StatusConflict))
+
+			status, _ = provision("other-instance-guid", serviceBroker.SyncPlans[1], false)
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, _ = provision("async-instance-guid", serviceBroker.AsyncPlans[0], true)
+			Expect(status).To(Equal(http.StatusAccepted))
+		})
+
+		It("keeps the plan's current response when only delaying it", func() {
+			serviceBroker.OnProvision(serviceBroker.AsyncPlans[0].ID).After(100 * time.Millisecond)
+
+			start := time.Now()
+			status, _ := provision("instance-guid", serviceBroker.AsyncPlans[0], true)
+			Expect(status).To(Equal(http.StatusAccepted))
+			Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+		})
+
+		It("responds with invalid JSON", func() {
+			serviceBroker.OnProvision("default").RespondWithRawBody(http.StatusOK, "{invalid")
+
+			_, body := provision("instance-guid", serviceBroker.SyncPlans[0], false)
+			Expect(body).To(Equal("{invalid"))
+		})
+
+		It("rejects synchronous requests to async only plans", func() {
+			serviceBroker.OnProvision(serviceBroker.AsyncPlans[0].ID).AsyncOnly()
+
+			status, _ := provision("instance-guid", serviceBroker.AsyncPlans[0], false)
+			Expect(status).To(Equal(422))
+		})
+	})
+
+	Describe("OnBind", func() {
+		It("responds with the given credentials", func() {
+			serviceBroker.OnBind("default").RespondWith(http.StatusCreated, map[string]interface{}{
+				"credentials": map[string]string{"password": "secret"},
+			})
+
+			status, body := request("PUT", "/v2/service_instances/instance-guid/service_bindings/binding-guid", `{"plan_id":"plan-guid"}`)
+			Expect(status).To(Equal(http.StatusCreated))
+			Expect(body).To(MatchJSON(`{"credentials":{"password":"secret"}}`))
+		})
+	})
+
+	Describe("OnLastOperation", func() {
+		It("responds with each state in turn, repeating the last", func() {
+			serviceBroker.OnLastOperation(serviceBroker.AsyncPlans[0].ID).Sequence(
+				LastOperationState("in progress", "starting"),
+				LastOperationState("in progress", "halfway"),
+				LastOperationState("failed", "broken"),
+			)
+			provision("instance-guid", serviceBroker.AsyncPlans[0], true)
+
+			for _, description := range []string{"starting", "halfway", "broken", "broken"} {
+				_, body := request("GET", "/v2/service_instances/instance-guid/last_operation", "")
+				Expect(body).To(ContainSubstring(description))
+			}
+		})
+	})
+
+	Describe("OnBindingLastOperation", func() {
+		It("binds asynchronously, responding with each state in turn and then the binding", func() {
+			serviceBroker.OnBind("default").RespondWith(http.StatusAccepted, map[string]string{})
+			serviceBroker.OnBindingLastOperation("default").Sequence(
+				LastOperationState("in progress", "binding"),
+				LastOperationState("succeeded", "bound"),
+			)
+			serviceBroker.OnGetBinding("default").RespondWith(http.StatusOK, map[string]interface{}{
+				"credentials": map[string]string{"password": "secret"},
+			})
+			bindingPath := "/v2/service_instances/instance-guid/service_bindings/binding-guid"
+
+			status, _ := request("PUT", bindingPath+"?accepts_incomplete=true", `{"plan_id":"plan-guid"}`)
+			Expect(status).To(Equal(http.StatusAccepted))
+
+			for _, description := range []string{"binding", "bound", "bound"} {
+				_, body := request("GET", bindingPath+"/last_operation", "")
+				Expect(body).To(ContainSubstring(description))
+			}
+
+			_, body := request("GET", bindingPath, "")
+			Expect(body).To(MatchJSON(`{"credentials":{"password":"secret"}}`))
+			Expect(serviceBroker.GetState().ServiceBindings["binding-guid"].FetchCount).To(Equal(3))
+		})
+	})
+
+	Describe("ServeCatalog", func() {
+		It("serves the changed catalog, leaving the other endpoints alone", func() {
+			catalog := serviceBroker.GetCatalog()
+			service := catalog["services"].([]interface{})[0].(map[string]interface{})
+			Expect(service).To(HaveKeyWithValue("id", serviceBroker.Service.ID))
+			delete(service, "description")
+
+			serviceBroker.ServeCatalog(catalog)
+
+			_, body := request("GET", "/v2/catalog", "")
+			Expect(body).To(ContainSubstring(serviceBroker.Service.ID))
+			Expect(body).NotTo(ContainSubstring(`"description":"fake service"`))
+
+			status, _ := provision("instance-guid", serviceBroker.SyncPlans[0], false)
+			Expect(status).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("GetState", func() {
+		It("lists the instances and bindings the broker was asked to create", func() {
+			provision("instance-guid", serviceBroker.SyncPlans[0], false)
+			request("PUT", "/v2/service_instances/instance-guid/service_bindings/binding-guid",
+				fmt.Sprintf(`{"plan_id":"%s","app_guid":"app-guid"}`, serviceBroker.SyncPlans[0].ID))
+
+			state := serviceBroker.GetState()
+			Expect(state.ServiceInstances).To(HaveKey("instance-guid"))
+			Expect(state.ServiceInstances["instance-guid"].ProvisionData).To(HaveKeyWithValue("plan_id", serviceBroker.SyncPlans[0].ID))
+			Expect(state.ServiceBindings).To(HaveKey("binding-guid"))
+			Expect(state.ServiceBindings["binding-guid"].InstanceId).To(Equal("instance-guid"))
+			Expect(state.ServiceBindings["binding-guid"].BindingData).To(HaveKeyWithValue("app_guid", "app-guid"))
+		})
+	})
+
+	Describe("GetRequests", func() {
+		It("lists the requests the broker received since it was reset", func() {
+			provision("instance-guid", serviceBroker.SyncPlans[0], false)
+			request("GET", "/v2/service_instances/instance-guid/last_operation", "")
+
+			requests := serviceBroker.GetRequests()
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[0].Method).To(Equal("PUT"))
+			Expect(requests[0].Path).To(Equal("/v2/service_instances/instance-guid"))
+			Expect(requests[0].Query).To(Equal("accepts_incomplete=false"))
+			Expect(requests[0].Body).To(ContainSubstring(serviceBroker.SyncPlans[0].ID))
+			Expect(requests[1].Path).To(Equal("/v2/service_instances/instance-guid/last_operation"))
+
+			request("POST", "/config/reset", "")
+			Expect(serviceBroker.GetRequests()).To(BeEmpty())
+		})
+	})
+
+	Describe("GetRequestsTo", func() {
+		It("lists the requests with the given method and path", func() {
+			provision("instance-guid", serviceBroker.SyncPlans[0], false)
+			provision("other-instance-guid", serviceBroker.SyncPlans[0], false)
+			request("DELETE", "/v2/service_instances/instance-guid?plan_id=plan-guid", "")
+
+			requests := serviceBroker.GetRequestsTo("PUT", "/v2/service_instances/instance-guid")
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Header("content-length")).NotTo(BeEmpty())
+			Expect(requests[0].JSONBody()).To(HaveKeyWithValue("plan_id", serviceBroker.SyncPlans[0].ID))
+
+			requests = serviceBroker.GetRequestsTo("DELETE", "/v2/service_instances/instance-guid")
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].QueryValues().Get("plan_id")).To(Equal("plan-guid"))
+		})
+	})
+})
@@ -0,0 +1,142 @@
+package services_test
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ServiceBroker", func() {
+	Describe("ToJSON", func() {
+		It("replaces every placeholder in cats.json", func() {
+			json := serviceBroker.ToJSON()
+			Expect(json).NotTo(ContainSubstring("<"))
+			Expect(json).To(ContainSubstring(serviceBroker.Service.Name))
+			for _, plan := range serviceBroker.Plans() {
+				Expect(json).To(ContainSubstring(plan.Name))
+				Expect(json).To(ContainSubstring(plan.ID))
+			}
+		})
+	})
+
+	Describe("Configure", func() {
+		BeforeEach(func() {
+			serviceBroker.Configure()
+		})
+
+		It("advertises the broker's service and plans in the catalog", func() {
+			status, catalog := request("GET", "/v2/catalog", "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(catalog).To(ContainSubstring(serviceBroker.Service.ID))
+			for _, plan := range serviceBroker.Plans() {
+				Expect(catalog).To(ContainSubstring(plan.ID))
+			}
+		})
+
+		It("provisions sync plans synchronously", func() {
+			status, body := provision("instance-guid", serviceBroker.SyncPlans[0], false)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(MatchJSON(`{"dashboard_url":"http://example.com"}`))
+		})
+
+		It("provisions async plans asynchronously, finishing after the configured number of fetches", func() {
+			status, _ := provision("instance-guid", serviceBroker.AsyncPlans[0], true)
+			Expect(status).To(Equal(http.StatusAccepted))
+
+			_, body := request("GET", "/v2/service_instances/instance-guid/last_operation", "")
+			Expect(body).To(MatchJSON(`{"state":"in progress","description":"not 100 percent done"}`))
+
+			_, body = request("GET", "/v2/service_instances/instance-guid/last_operation", "")
+			Expect(body).To(MatchJSON(`{"state":"succeeded","description":"100 percent done"}`))
+		})
+
+		It("binds to provisioned instances", func() {
+			provision("instance-guid", serviceBroker.SyncPlans[0], false)
+
+			status, body := request("PUT", "/v2/service_instances/instance-guid/service_bindings/binding-guid",
+				fmt.Sprintf(`{"plan_id":"%s"}`, serviceBroker.SyncPlans[0].ID))
+			Expect(status).To(Equal(http.StatusCreated))
+			Expect(body).To(ContainSubstring("fake-user"))
+		})
+
+		It("serves instances and bindings with the parameters they were created or updated with", func() {
+			request("PUT", "/v2/service_instances/instance-guid",
+				fmt.Sprintf(`{"plan_id":"%s","parameters":{"size":"small"}}`, serviceBroker.SyncPlans[0].ID))
+			request("PATCH", "/v2/service_instances/instance-guid",
+				fmt.Sprintf(`{"plan_id":"%s","parameters":{"size":"large"}}`, serviceBroker.SyncPlans[0].ID))
+			request("PUT", "/v2/service_instances/instance-guid/service_bindings/binding-guid",
+				fmt.Sprintf(`{"plan_id":"%s","parameters":{"role":"reader"}}`, serviceBroker.SyncPlans[0].ID))
+
+			status, body := request("GET", "/v2/service_instances/instance-guid", "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(MatchJSON(`{"parameters":{"size":"large"}}`))
+
+			status, body = request("GET", "/v2/service_instances/instance-guid/service_bindings/binding-guid", "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring("fake-user"))
+			Expect(body).To(ContainSubstring(`"parameters":{"role":"reader"}`))
+		})
+
+		It("updates instances that were created without provision data", func() {
+			status, _ := request("PUT", "/v2/service_instances/instance-guid", "null")
+			Expect(status).To(Equal(http.StatusOK))
+			status, _ = request("POST", "/config", `{"service_instances":{"injected-guid":{"fetch_count":0}}}`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			for _, instanceId := range []string{"instance-guid", "injected-guid"} {
+				status, _ = request("PATCH", "/v2/service_instances/"+instanceId,
+					fmt.Sprintf(`{"plan_id":"%s","parameters":{"size":"large"}}`, serviceBroker.SyncPlans[0].ID))
+				Expect(status).To(Equal(http.StatusOK))
+			}
+
+			status, _ = request("GET", "/config", "")
+			Expect(status).To(Equal(http.StatusOK))
+		})
+
+		It("does not serve instances that were deprovisioned", func() {
+			provision("instance-guid", serviceBroker.SyncPlans[0], false)
+			request("DELETE", "/v2/service_instances/instance-guid", "")
+
+			status, _ := request("GET", "/v2/service_instances/instance-guid", "")
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+
+		Context("when a plan's behavior is changed", func() {
+			BeforeEach(func() {
+				status, _ := request("POST", "/config", fmt.Sprintf(`{"behaviors":{"provision":{
+					"%s":{"sleep_seconds":0,"status":202,"body":{},"async_only":true},
+					"default":{"sleep_seconds":0,"status":500,"raw_body":"not json"}
+				}}}`, serviceBroker.AsyncPlans[0].ID))
+				Expect(status).To(Equal(http.StatusOK))
+			})
+
+			It("rejects requests to async only plans that do not accept incomplete responses", func() {
+				status, body := provision("instance-guid", serviceBroker.AsyncPlans[0], false)
+				Expect(status).To(Equal(422))
+				Expect(body).To(ContainSubstring("AsyncRequired"))
+
+				status, _ = provision("instance-guid", serviceBroker.AsyncPlans[0], true)
+				Expect(status).To(Equal(http.StatusAccepted))
+			})
+
+			It("responds with the raw body when no body is configured", func() {
+				status, body := provision("instance-guid", serviceBroker.SyncPlans[0], false)
+				Expect(status).To(Equal(http.StatusInternalServerError))
+				Expect(body).To(Equal("not json"))
+			})
+
+			It("returns to its initial configuration when reset", func() {
+				status, _ := request("POST", "/config/reset", "")
+				Expect(status).To(Equal(http.StatusOK))
+
+				_, catalog := request("GET", "/v2/catalog", "")
+				Expect(catalog).NotTo(ContainSubstring(serviceBroker.Service.ID))
+
+				status, _ = provision("instance-guid", serviceBroker.SyncPlans[0], false)
+				Expect(status).To(Equal(http.StatusOK))
+			})
+		})
+	})
+})
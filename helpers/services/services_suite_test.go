@@ -0,0 +1,64 @@
+package services_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/services"
+
+	"github.com/cloudfoundry/cf-acceptance-tests/assets/go-service-broker/broker"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestServices(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Services Helpers Suite")
+}
+
+const brokerPath = "../../assets/go-service-broker"
+
+// Every spec drives a Go broker served in-process.
+var (
+	server        *httptest.Server
+	serviceBroker ServiceBroker
+)
+
+var _ = BeforeEach(func() {
+	defaultConfig, err := ioutil.ReadFile(brokerPath + "/data.json")
+	Expect(err).NotTo(HaveOccurred())
+
+	goBroker, err := broker.New(defaultConfig)
+	Expect(err).NotTo(HaveOccurred())
+	server = httptest.NewServer(goBroker)
+
+	serviceBroker = NewServiceBroker("broker-name", brokerPath, nil)
+	serviceBroker.Url = server.URL
+})
+
+var _ = AfterEach(func() {
+	server.Close()
+})
+
+func request(method, path, body string) (int, string) {
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	Expect(err).NotTo(HaveOccurred())
+
+	res, err := http.DefaultClient.Do(req)
+	Expect(err).NotTo(HaveOccurred())
+	defer res.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(res.Body)
+	Expect(err).NotTo(HaveOccurred())
+	return res.StatusCode, string(responseBody)
+}
+
+func provision(instanceId string, plan Plan, acceptsIncomplete bool) (int, string) {
+	return request("PUT", fmt.Sprintf("/v2/service_instances/%s?accepts_incomplete=%t", instanceId, acceptsIncomplete),
+		fmt.Sprintf(`{"service_id":"%s","plan_id":"%s"}`, serviceBroker.Service.ID, plan.ID))
+}
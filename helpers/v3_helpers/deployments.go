@@ -0,0 +1,48 @@
+package v3_helpers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+type Deployment struct {
+	Guid    string `json:"guid"`
+	State   string `json:"state"`
+	Droplet struct {
+		Guid string `json:"guid"`
+	} `json:"droplet"`
+}
+
+// CreateDeployment rolls the app out to the droplet, which becomes the app's
+// current droplet unless the deployment is cancelled.
+func CreateDeployment(appGuid, dropletGuid string) string {
+	deploymentBody := fmt.Sprintf(`{"droplet": {"guid": "%s"}, "relationships": {"app": {"data": {"guid": "%s"}}}}`, dropletGuid, appGuid)
+	session := cf.Cf("curl", "/v3/deployments", "-X", "POST", "-d", deploymentBody)
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var deployment Deployment
+	err := json.Unmarshal(session.Out.Contents(), &deployment)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(deployment.Guid).NotTo(BeEmpty())
+	return deployment.Guid
+}
+
+func GetDeployment(deploymentGuid string) Deployment {
+	session := cf.Cf("curl", fmt.Sprintf("/v3/deployments/%s", deploymentGuid))
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var deployment Deployment
+	err := json.Unmarshal(session.Out.Contents(), &deployment)
+	Expect(err).NotTo(HaveOccurred())
+	return deployment
+}
+
+func CancelDeployment(deploymentGuid string) {
+	cancelPath := fmt.Sprintf("/v3/deployments/%s/actions/cancel", deploymentGuid)
+	Expect(cf.Cf("curl", cancelPath, "-X", "POST").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+}
@@ -0,0 +1,55 @@
+package v3_helpers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+type Droplet struct {
+	Guid         string             `json:"guid"`
+	State        string             `json:"state"`
+	Error        string             `json:"error"`
+	Buildpacks   []DropletBuildpack `json:"buildpacks"`
+	ProcessTypes map[string]string  `json:"process_types"`
+}
+
+type DropletBuildpack struct {
+	Name          string `json:"name"`
+	BuildpackName string `json:"buildpack_name"`
+	DetectOutput  string `json:"detect_output"`
+	Version       string `json:"version"`
+}
+
+// StageWithBuildpacks stages a package with an ordered list of buildpacks.
+// All but the last are only used to supply dependencies, and the last
+// provides the start command.
+func StageWithBuildpacks(packageGuid string, buildpacks ...string) string {
+	buildpacksJson, err := json.Marshal(buildpacks)
+	Expect(err).NotTo(HaveOccurred())
+
+	stageBody := fmt.Sprintf(`{"lifecycle":{ "type": "buildpack", "data": { "buildpacks": %s } }}`, buildpacksJson)
+	stageUrl := fmt.Sprintf("/v3/packages/%s/droplets", packageGuid)
+	session := cf.Cf("curl", stageUrl, "-X", "POST", "-d", stageBody)
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var droplet Droplet
+	err = json.Unmarshal(session.Out.Contents(), &droplet)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(droplet.Guid).NotTo(BeEmpty(), string(session.Out.Contents()))
+	return droplet.Guid
+}
+
+func GetDroplet(dropletGuid string) Droplet {
+	session := cf.Cf("curl", fmt.Sprintf("/v3/droplets/%s", dropletGuid))
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var droplet Droplet
+	err := json.Unmarshal(session.Out.Contents(), &droplet)
+	Expect(err).NotTo(HaveOccurred())
+	return droplet
+}
@@ -0,0 +1,69 @@
+package v3_helpers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+type ProcessStats struct {
+	Instances []ProcessInstance `json:"resources"`
+}
+
+type ProcessInstance struct {
+	Index         int            `json:"index"`
+	State         string         `json:"state"`
+	Uptime        int            `json:"uptime"`
+	Host          string         `json:"host"`
+	Port          int            `json:"port"`
+	InstancePorts []InstancePort `json:"instance_ports"`
+	MemQuota      int            `json:"mem_quota"`
+	DiskQuota     int            `json:"disk_quota"`
+	FdsQuota      int            `json:"fds_quota"`
+	Usage         struct {
+		Time string  `json:"time"`
+		Cpu  float64 `json:"cpu"`
+		Mem  int     `json:"mem"`
+		Disk int     `json:"disk"`
+	} `json:"usage"`
+}
+
+type InstancePort struct {
+	External int `json:"external"`
+	Internal int `json:"internal"`
+}
+
+// GetProcessStats works for the web process of apps pushed through the v2
+// API too, as its guid is the same as the app's.
+func GetProcessStats(processGuid string) ProcessStats {
+	return getProcessStats(fmt.Sprintf("/v3/processes/%s/stats", processGuid))
+}
+
+func GetProcessStatsByType(appGuid, processType string) ProcessStats {
+	return getProcessStats(fmt.Sprintf("/v3/apps/%s/processes/%s/stats", appGuid, processType))
+}
+
+// InstanceByIndex returns the stats of the instance with the given index,
+// and false if there is no such instance.
+func (stats ProcessStats) InstanceByIndex(index int) (ProcessInstance, bool) {
+	for _, instance := range stats.Instances {
+		if instance.Index == index {
+			return instance, true
+		}
+	}
+	return ProcessInstance{}, false
+}
+
+func getProcessStats(statsPath string) ProcessStats {
+	session := cf.Cf("curl", statsPath)
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var stats ProcessStats
+	err := json.Unmarshal(session.Out.Contents(), &stats)
+	Expect(err).NotTo(HaveOccurred())
+	return stats
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 
 	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
 )
 
 type ProcessList struct {
@@ -12,10 +15,22 @@ type ProcessList struct {
 }
 
 type Process struct {
-	Guid    string `json:"guid"`
-	Type    string `json:"type"`
-	Command string `json:"command"`
-	Name    string `json:"-"`
+	Guid        string      `json:"guid"`
+	Type        string      `json:"type"`
+	Command     string      `json:"command"`
+	Instances   int         `json:"instances"`
+	MemoryInMb  int         `json:"memory_in_mb"`
+	DiskInMb    int         `json:"disk_in_mb"`
+	HealthCheck HealthCheck `json:"health_check"`
+	Name        string      `json:"-"`
+}
+
+type HealthCheck struct {
+	Type string `json:"type"`
+	Data struct {
+		Timeout  int    `json:"timeout"`
+		Endpoint string `json:"endpoint"`
+	} `json:"data"`
 }
 
 func GetProcesses(appGuid, appName string) []Process {
@@ -41,3 +56,45 @@ func GetProcessByType(processes []Process, processType string) Process {
 	}
 	return Process{}
 }
+
+func GetProcessByGuid(processGuid string) Process {
+	session := cf.Cf("curl", fmt.Sprintf("/v3/processes/%s", processGuid))
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var process Process
+	err := json.Unmarshal(session.Out.Contents(), &process)
+	Expect(err).NotTo(HaveOccurred())
+	return process
+}
+
+// ScaleProcessByGuid leaves any of instances, memoryInMb and diskInMb that
+// are empty unchanged.
+func ScaleProcessByGuid(processGuid, instances, memoryInMb, diskInMb string) {
+	scalePath := fmt.Sprintf("/v3/processes/%s/scale", processGuid)
+	scaleBody := scaleProcessBody(instances, memoryInMb, diskInMb)
+	Expect(cf.Cf("curl", scalePath, "-X", "PUT", "-d", scaleBody).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+}
+
+// UpdateProcessHealthCheck sets the health check type of a process. The
+// endpoint only applies to "http" health checks, and is left out when empty,
+// as is a zero timeout.
+func UpdateProcessHealthCheck(processGuid, healthCheckType, endpoint string, timeout int) {
+	data := map[string]interface{}{}
+	if endpoint != "" {
+		data["endpoint"] = endpoint
+	}
+	if timeout > 0 {
+		data["timeout"] = timeout
+	}
+
+	processBody, err := json.Marshal(map[string]interface{}{
+		"health_check": map[string]interface{}{
+			"type": healthCheckType,
+			"data": data,
+		},
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	processPath := fmt.Sprintf("/v3/processes/%s", processGuid)
+	Expect(cf.Cf("curl", processPath, "-X", "PATCH", "-d", string(processBody)).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+}
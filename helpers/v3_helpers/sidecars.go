@@ -0,0 +1,50 @@
+package v3_helpers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+type SidecarList struct {
+	Sidecars []Sidecar `json:"resources"`
+}
+
+type Sidecar struct {
+	Guid         string   `json:"guid,omitempty"`
+	Name         string   `json:"name"`
+	Command      string   `json:"command"`
+	ProcessTypes []string `json:"process_types"`
+}
+
+func CreateSidecar(appGuid, name, command string, processTypes []string) string {
+	sidecarBody, err := json.Marshal(Sidecar{
+		Name:         name,
+		Command:      command,
+		ProcessTypes: processTypes,
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	session := cf.Cf("curl", fmt.Sprintf("/v3/apps/%s/sidecars", appGuid), "-X", "POST", "-d", string(sidecarBody))
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var sidecar Sidecar
+	err = json.Unmarshal(session.Out.Contents(), &sidecar)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(sidecar.Guid).NotTo(BeEmpty())
+	return sidecar.Guid
+}
+
+func GetSidecars(appGuid string) []Sidecar {
+	session := cf.Cf("curl", fmt.Sprintf("/v3/apps/%s/sidecars", appGuid))
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	sidecars := SidecarList{}
+	err := json.Unmarshal(session.Out.Contents(), &sidecars)
+	Expect(err).NotTo(HaveOccurred())
+	return sidecars.Sidecars
+}
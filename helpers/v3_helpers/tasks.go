@@ -0,0 +1,59 @@
+package v3_helpers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+type Task struct {
+	Guid       string `json:"guid"`
+	SequenceId int    `json:"sequence_id"`
+	Name       string `json:"name"`
+	Command    string `json:"command"`
+	State      string `json:"state"`
+	MemoryInMb int    `json:"memory_in_mb"`
+	Result     struct {
+		FailureReason string `json:"failure_reason"`
+	} `json:"result"`
+}
+
+// CreateTask leaves the name and memory to the Cloud Controller's defaults
+// when they are empty or zero. The raw response is returned alongside the
+// task so that rejected requests can be asserted on.
+func CreateTask(appGuid, command, name string, memoryInMb int) (Task, []byte) {
+	taskBody := map[string]interface{}{"command": command}
+	if name != "" {
+		taskBody["name"] = name
+	}
+	if memoryInMb > 0 {
+		taskBody["memory_in_mb"] = memoryInMb
+	}
+	postBody, err := json.Marshal(taskBody)
+	Expect(err).NotTo(HaveOccurred())
+
+	session := cf.Cf("curl", fmt.Sprintf("/v3/apps/%s/tasks", appGuid), "-X", "POST", "-d", string(postBody))
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var task Task
+	json.Unmarshal(session.Out.Contents(), &task)
+	return task, session.Out.Contents()
+}
+
+func GetTask(taskGuid string) Task {
+	session := cf.Cf("curl", fmt.Sprintf("/v3/tasks/%s", taskGuid))
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	var task Task
+	err := json.Unmarshal(session.Out.Contents(), &task)
+	Expect(err).NotTo(HaveOccurred())
+	return task
+}
+
+func CancelTask(taskGuid string) {
+	Expect(cf.Cf("curl", fmt.Sprintf("/v3/tasks/%s/cancel", taskGuid), "-X", "PUT").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+}
@@ -3,6 +3,9 @@ package v3_helpers
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
 	"strings"
 
 	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
@@ -157,7 +160,7 @@ func AssignDropletToApp(appGuid, dropletGuid string) {
 	Expect(cf.Cf("curl", appUpdatePath, "-X", "PUT", "-d", appUpdateBody).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
 
 	for _, process := range GetProcesses(appGuid, "") {
-		ScaleProcess(appGuid, process.Type, DEFAULT_MEMORY_LIMIT)
+		ScaleProcess(appGuid, process.Type, "", DEFAULT_MEMORY_LIMIT, "")
 	}
 }
 
@@ -169,12 +172,60 @@ func FetchRecentLogs(appGuid, oauthToken string, config helpers.Config) *Session
 	return session
 }
 
-func ScaleProcess(appGuid, processType, memoryInMb string) {
+// ScaleProcess leaves any of instances, memoryInMb and diskInMb that are
+// empty unchanged.
+func ScaleProcess(appGuid, processType, instances, memoryInMb, diskInMb string) {
 	scalePath := fmt.Sprintf("/v3/apps/%s/processes/%s/scale", appGuid, processType)
-	scaleBody := fmt.Sprintf(`{"memory_in_mb":"%s"}`, memoryInMb)
+	scaleBody := scaleProcessBody(instances, memoryInMb, diskInMb)
 	Expect(cf.Cf("curl", scalePath, "-X", "PUT", "-d", scaleBody).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
 }
 
+func scaleProcessBody(instances, memoryInMb, diskInMb string) string {
+	var fields []string
+	if instances != "" {
+		fields = append(fields, fmt.Sprintf(`"instances":%s`, instances))
+	}
+	if memoryInMb != "" {
+		fields = append(fields, fmt.Sprintf(`"memory_in_mb":%s`, memoryInMb))
+	}
+	if diskInMb != "" {
+		fields = append(fields, fmt.Sprintf(`"disk_in_mb":%s`, diskInMb))
+	}
+	return "{" + strings.Join(fields, ",") + "}"
+}
+
+func ApplyManifest(spaceGuid, manifest string) {
+	manifestFile, err := ioutil.TempFile("", "cats-manifest")
+	Expect(err).NotTo(HaveOccurred())
+	defer os.Remove(manifestFile.Name())
+
+	_, err = manifestFile.WriteString(manifest)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(manifestFile.Close()).To(Succeed())
+
+	applyManifestPath := fmt.Sprintf("/v3/spaces/%s/actions/apply_manifest", spaceGuid)
+	session := cf.Cf("curl", applyManifestPath, "-i", "-X", "POST", "-H", "Content-Type: application/x-yaml", "-d", "@"+manifestFile.Name())
+	Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	jobPath := regexp.MustCompile(`/v3/jobs/[\w-]+`).Find(session.Out.Contents())
+	Expect(jobPath).NotTo(BeNil(), "expected apply_manifest to return a job")
+	WaitForJob(string(jobPath))
+}
+
+func WaitForJob(jobPath string) {
+	Eventually(func() string {
+		session := cf.Cf("curl", jobPath)
+		Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+		var job struct {
+			State string `json:"state"`
+		}
+		Expect(json.Unmarshal(session.Out.Contents(), &job)).To(Succeed(), string(session.Out.Contents()))
+		Expect(job.State).NotTo(Equal("FAILED"), string(session.Out.Contents()))
+		return job.State
+	}, CF_PUSH_TIMEOUT).Should(Equal("COMPLETE"))
+}
+
 func CreateRoute(space, domain, host string) {
 	Expect(cf.Cf("create-route", space, domain, "-n", host).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
 }
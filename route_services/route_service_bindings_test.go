@@ -0,0 +1,235 @@
+package route_services
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/cloudfoundry-incubator/cf-routing-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/runner"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/routing_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe(deaUnsupportedTag+"Route Service Bindings", func() {
+	var (
+		serviceInstanceName           string
+		brokerName                    string
+		routeServiceName              string
+		helloRoutingAsset             = assets.NewAssets().HelloRouting
+		configurableRouteServiceAsset = assets.NewAssets().ConfigurableRouteService
+	)
+
+	BeforeEach(func() {
+		routeServiceName = GenerateAppName()
+		brokerName = generator.PrefixedRandomName("RATS-BROKER-")
+		serviceInstanceName = generator.PrefixedRandomName("RATS-SERVICE-")
+
+		serviceName := generator.PrefixedRandomName("RATS-SERVICE-")
+		brokerAppName := GenerateAppName()
+
+		createServiceBroker(brokerName, brokerAppName, serviceName)
+		createServiceInstance(serviceInstanceName, serviceName)
+
+		PushApp(routeServiceName, configurableRouteServiceAsset, config.GoBuildpackName, config.AppsDomain, CF_PUSH_TIMEOUT)
+		configureBroker(brokerAppName, routeServiceName)
+	})
+
+	AfterEach(func() {
+		AppReport(routeServiceName, DEFAULT_TIMEOUT)
+
+		deleteServiceInstance(serviceInstanceName)
+		deleteServiceBroker(brokerName)
+		DeleteApp(routeServiceName, DEFAULT_TIMEOUT)
+	})
+
+	Context("when the route has a context path", func() {
+		var (
+			apps      routing_helpers.ContextPathApps
+			app2Path  = "/app2"
+			routeGuid string
+		)
+
+		BeforeEach(func() {
+			apps = routing_helpers.PushContextPathApps(helloRoutingAsset, config.RubyBuildpackName, config.AppsDomain,
+				[]string{app2Path}, CF_PUSH_TIMEOUT, DEFAULT_TIMEOUT)
+
+			routeGuid = GetRouteGuid(apps.Hostname, app2Path, DEFAULT_TIMEOUT)
+			bindRouteOnDomainToService(config.AppsDomain, apps.Hostname, app2Path, routeGuid, serviceInstanceName)
+		})
+
+		AfterEach(func() {
+			apps.Report(DEFAULT_TIMEOUT)
+
+			if routeIsBound(routeGuid) {
+				unbindRouteOnDomainFromService(config.AppsDomain, apps.Hostname, app2Path, routeGuid, serviceInstanceName)
+			}
+			apps.Delete(DEFAULT_TIMEOUT)
+		})
+
+		It("only sends requests for the bound path to the route service", func() {
+			app2 := apps.PathApps[app2Path]
+			appUri := func(path string) string {
+				return helpers.AppUri(apps.Hostname, path)
+			}
+
+			Eventually(func() []routeServiceRequest {
+				clearRouteServiceRequests(routeServiceName)
+				Expect(curlRoute(appUri(app2Path))).To(ContainSubstring(app2))
+				return routeServiceRequests(routeServiceName)
+			}, DEFAULT_TIMEOUT).ShouldNot(BeEmpty())
+
+			clearRouteServiceRequests(routeServiceName)
+			Expect(curlRoute(appUri(app2Path))).To(ContainSubstring(app2))
+			Expect(curlRoute(appUri("/"))).To(ContainSubstring(apps.RootApp))
+
+			requests := routeServiceRequests(routeServiceName)
+			Expect(requests).To(HaveLen(1))
+			Expect(strings.ToLower(requests[0].ForwardedUrl)).To(Equal(strings.ToLower(appUri(app2Path))))
+
+			By("unbinding the route service")
+			unbindRouteOnDomainFromService(config.AppsDomain, apps.Hostname, app2Path, routeGuid, serviceInstanceName)
+
+			Eventually(func() []routeServiceRequest {
+				clearRouteServiceRequests(routeServiceName)
+				Expect(curlRoute(appUri(app2Path))).To(ContainSubstring(app2))
+				return routeServiceRequests(routeServiceName)
+			}, DEFAULT_TIMEOUT).Should(BeEmpty())
+		})
+	})
+
+	Context("when the route is a wildcard route", func() {
+		var (
+			wildcardApp  string
+			regularApp   string
+			domainName   string
+			regularHost  = "bar"
+			routeGuid    string
+			spaceName    string
+			orgName      string
+			wildcardHost = "*"
+		)
+
+		routeUri := func(hostname, path string) string {
+			return config.Protocol() + hostname + "." + domainName + path
+		}
+
+		BeforeEach(func() {
+			orgName = context.RegularUserContext().Org
+			spaceName = context.RegularUserContext().Space
+
+			domainName = generator.RandomName() + "." + config.AppsDomain
+			cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+				Expect(cf.Cf("create-shared-domain", domainName).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+				Expect(cf.Cf("target", "-o", orgName).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+				Expect(cf.Cf("create-route", spaceName, domainName, "-n", wildcardHost).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			})
+
+			wildcardApp = GenerateAppName()
+			PushApp(wildcardApp, helloRoutingAsset, config.RubyBuildpackName, config.AppsDomain, CF_PUSH_TIMEOUT)
+			regularApp = GenerateAppName()
+			PushApp(regularApp, helloRoutingAsset, config.RubyBuildpackName, config.AppsDomain, CF_PUSH_TIMEOUT)
+
+			Expect(cf.Cf("map-route", wildcardApp, domainName, "-n", wildcardHost).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			Expect(cf.Cf("map-route", regularApp, domainName, "-n", regularHost).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+			domainGuid := GetDomainGuid(domainName, DEFAULT_TIMEOUT)
+			routeGuid = GetGuid(fmt.Sprintf("/v2/routes?q=host:%s&q=domain_guid:%s", wildcardHost, domainGuid), DEFAULT_TIMEOUT)
+			Expect(routeGuid).NotTo(BeEmpty())
+
+			bindRouteOnDomainToService(domainName, wildcardHost, "", routeGuid, serviceInstanceName)
+		})
+
+		AfterEach(func() {
+			AppReport(wildcardApp, DEFAULT_TIMEOUT)
+			AppReport(regularApp, DEFAULT_TIMEOUT)
+
+			if routeIsBound(routeGuid) {
+				unbindRouteOnDomainFromService(domainName, wildcardHost, "", routeGuid, serviceInstanceName)
+			}
+			DeleteApp(wildcardApp, DEFAULT_TIMEOUT)
+			DeleteApp(regularApp, DEFAULT_TIMEOUT)
+
+			cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+				Expect(cf.Cf("target", "-o", orgName).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+				Expect(cf.Cf("delete-shared-domain", domainName, "-f").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			})
+		})
+
+		It("only sends requests matched by the wildcard to the route service", func() {
+			Eventually(func() []routeServiceRequest {
+				clearRouteServiceRequests(routeServiceName)
+				Expect(curlRoute(routeUri("foo", "/"))).To(ContainSubstring(wildcardApp))
+				return routeServiceRequests(routeServiceName)
+			}, DEFAULT_TIMEOUT).ShouldNot(BeEmpty())
+
+			clearRouteServiceRequests(routeServiceName)
+			Expect(curlRoute(routeUri("foo", "/"))).To(ContainSubstring(wildcardApp))
+			Expect(curlRoute(routeUri("foo.baz", "/"))).To(ContainSubstring(wildcardApp))
+			Expect(curlRoute(routeUri(regularHost, "/"))).To(ContainSubstring(regularApp))
+
+			requests := routeServiceRequests(routeServiceName)
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[0].ForwardedUrl).To(Equal(routeUri("foo", "/")))
+			Expect(requests[1].ForwardedUrl).To(Equal(routeUri("foo.baz", "/")))
+
+			By("unbinding the route service")
+			unbindRouteOnDomainFromService(domainName, wildcardHost, "", routeGuid, serviceInstanceName)
+
+			Eventually(func() []routeServiceRequest {
+				clearRouteServiceRequests(routeServiceName)
+				Expect(curlRoute(routeUri("foo", "/"))).To(ContainSubstring(wildcardApp))
+				return routeServiceRequests(routeServiceName)
+			}, DEFAULT_TIMEOUT).Should(BeEmpty())
+		})
+	})
+})
+
+// bindRouteOnDomainToService binds the route with hostname and path, which
+// may be empty, on domain. routeGuid is the route's guid, used to wait for
+// the binding.
+func bindRouteOnDomainToService(domain, hostname, path, routeGuid, serviceInstanceName string) {
+	Expect(cf.Cf(routeServiceArgs("bind-route-service", domain, hostname, path, serviceInstanceName)...).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	Eventually(func() bool {
+		return routeIsBound(routeGuid)
+	}, DEFAULT_TIMEOUT, "1s").Should(BeTrue())
+}
+
+func unbindRouteOnDomainFromService(domain, hostname, path, routeGuid, serviceInstanceName string) {
+	Expect(cf.Cf(routeServiceArgs("unbind-route-service", domain, hostname, path, serviceInstanceName)...).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	Eventually(func() bool {
+		return routeIsBound(routeGuid)
+	}, DEFAULT_TIMEOUT, "1s").Should(BeFalse())
+}
+
+func routeServiceArgs(command, domain, hostname, path, serviceInstanceName string) []string {
+	args := []string{command, domain, serviceInstanceName, "-f", "--hostname", hostname}
+	if path != "" {
+		args = append(args, "--path", path)
+	}
+	return args
+}
+
+func routeIsBound(routeGuid string) bool {
+	response := cf.Cf("curl", fmt.Sprintf("/v2/routes/%s", routeGuid))
+	Expect(response.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+	return !strings.Contains(string(response.Out.Contents()), `"service_instance_guid": null`)
+}
+
+func clearRouteServiceRequests(routeServiceName string) {
+	helpers.CurlApp(routeServiceName, "/requests", "-X", "DELETE")
+}
+
+func curlRoute(uri string) string {
+	curl := runner.Curl(uri).Wait(DEFAULT_TIMEOUT)
+	Expect(curl).To(Exit(0))
+	return string(curl.Out.Contents())
+}
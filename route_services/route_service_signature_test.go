@@ -0,0 +1,145 @@
+package route_services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/cf-routing-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/runner"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+type routeServiceRequest struct {
+	Method       string `json:"method"`
+	ForwardedUrl string `json:"forwarded_url"`
+	Signature    string `json:"signature"`
+	Metadata     string `json:"metadata"`
+}
+
+var _ = Describe(deaUnsupportedTag+"Route Service Signatures", func() {
+	var (
+		serviceInstanceName           string
+		brokerName                    string
+		appName                       string
+		routeServiceName              string
+		golangAsset                   = assets.NewAssets().Golang
+		configurableRouteServiceAsset = assets.NewAssets().ConfigurableRouteService
+	)
+
+	BeforeEach(func() {
+		routeServiceName = GenerateAppName()
+		brokerName = generator.PrefixedRandomName("RATS-BROKER-")
+		serviceInstanceName = generator.PrefixedRandomName("RATS-SERVICE-")
+		appName = GenerateAppName()
+
+		serviceName := generator.PrefixedRandomName("RATS-SERVICE-")
+		brokerAppName := GenerateAppName()
+
+		createServiceBroker(brokerName, brokerAppName, serviceName)
+		createServiceInstance(serviceInstanceName, serviceName)
+
+		PushAppNoStart(appName, golangAsset, config.GoBuildpackName, config.AppsDomain, CF_PUSH_TIMEOUT)
+		EnableDiego(appName, DEFAULT_TIMEOUT)
+		StartApp(appName, CF_PUSH_TIMEOUT)
+
+		PushApp(routeServiceName, configurableRouteServiceAsset, config.GoBuildpackName, config.AppsDomain, CF_PUSH_TIMEOUT)
+		configureBroker(brokerAppName, routeServiceName)
+
+		bindRouteToService(appName, serviceInstanceName)
+	})
+
+	AfterEach(func() {
+		AppReport(appName, DEFAULT_TIMEOUT)
+		AppReport(routeServiceName, DEFAULT_TIMEOUT)
+
+		unbindRouteFromService(appName, serviceInstanceName)
+		deleteServiceInstance(serviceInstanceName)
+		deleteServiceBroker(brokerName)
+		DeleteApp(appName, DEFAULT_TIMEOUT)
+		DeleteApp(routeServiceName, DEFAULT_TIMEOUT)
+	})
+
+	It("sends the forwarded url, signature and metadata headers to the route service", func() {
+		Eventually(func() string {
+			return helpers.CurlAppRoot(appName)
+		}, DEFAULT_TIMEOUT).Should(ContainSubstring("go, world"))
+
+		requests := routeServiceRequests(routeServiceName)
+		Expect(requests).NotTo(BeEmpty())
+
+		request := requests[len(requests)-1]
+		Expect(request.ForwardedUrl).To(ContainSubstring(fmt.Sprintf("%s.%s", appName, config.AppsDomain)))
+		Expect(request.Signature).NotTo(BeEmpty())
+		Expect(request.Metadata).NotTo(BeEmpty())
+	})
+
+	Context("when the route service tampers with the signature", func() {
+		BeforeEach(func() {
+			configureRouteService(routeServiceName, `{"signature": "tamper"}`)
+		})
+
+		It("rejects the forwarded request", func() {
+			Eventually(func() string {
+				return curlAppStatusCode(appName, "/", DEFAULT_TIMEOUT)
+			}, DEFAULT_TIMEOUT).Should(Equal("400"))
+
+			Expect(routeServiceRequests(routeServiceName)).NotTo(BeEmpty())
+			Expect(helpers.CurlAppRoot(appName)).NotTo(ContainSubstring("go, world"))
+		})
+	})
+
+	Context("when the route service strips the signature", func() {
+		BeforeEach(func() {
+			configureRouteService(routeServiceName, `{"signature": "strip"}`)
+		})
+
+		It("rejects the forwarded request", func() {
+			Eventually(func() string {
+				return curlAppStatusCode(appName, "/", DEFAULT_TIMEOUT)
+			}, DEFAULT_TIMEOUT).Should(MatchRegexp("^[45][0-9][0-9]$"))
+
+			requests := routeServiceRequests(routeServiceName)
+			Expect(requests).NotTo(BeEmpty())
+			Expect(requests[0].Signature).NotTo(BeEmpty())
+			Expect(helpers.CurlAppRoot(appName)).NotTo(ContainSubstring("go, world"))
+		})
+	})
+
+	Context("when the route service forwards the request after the signature has expired", func() {
+		var delay time.Duration
+
+		BeforeEach(func() {
+			delay = ROUTE_SERVICE_SIGNATURE_TIMEOUT + 5*time.Second
+			configureRouteService(routeServiceName, fmt.Sprintf(`{"delay_seconds": %d}`, int(delay.Seconds())))
+		})
+
+		It("rejects the forwarded request", func() {
+			Expect(curlAppStatusCode(appName, "/", delay+DEFAULT_TIMEOUT)).To(Equal("400"))
+			Expect(routeServiceRequests(routeServiceName)).NotTo(BeEmpty())
+		})
+	})
+})
+
+func configureRouteService(routeServiceName, behavior string) {
+	helpers.CurlApp(routeServiceName, "/behavior", "-X", "PUT", "-d", behavior)
+}
+
+func routeServiceRequests(routeServiceName string) []routeServiceRequest {
+	var requests []routeServiceRequest
+	err := json.Unmarshal([]byte(helpers.CurlApp(routeServiceName, "/requests")), &requests)
+	Expect(err).NotTo(HaveOccurred())
+	return requests
+}
+
+func curlAppStatusCode(appName, path string, timeout time.Duration) string {
+	curl := runner.Curl(helpers.AppUri(appName, path), "-o", "/dev/null", "-w", "%{http_code}").Wait(timeout)
+	Expect(curl).To(Exit(0))
+	return strings.TrimSpace(string(curl.Out.Contents()))
+}
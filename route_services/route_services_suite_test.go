@@ -16,6 +16,10 @@ var (
 	DEFAULT_TIMEOUT = 30 * time.Second
 	CF_PUSH_TIMEOUT = 2 * time.Minute
 
+	// The gorouter rejects route service signatures older than its
+	// route_services_timeout, which defaults to 60 seconds.
+	ROUTE_SERVICE_SIGNATURE_TIMEOUT = 60 * time.Second
+
 	context helpers.SuiteContext
 	config  helpers.Config
 )
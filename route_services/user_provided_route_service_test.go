@@ -0,0 +1,101 @@
+package route_services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	. "github.com/cloudfoundry-incubator/cf-routing-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe(deaUnsupportedTag+"User-Provided Route Services", func() {
+	var (
+		serviceInstanceName           string
+		appName                       string
+		routeServiceNames             []string
+		golangAsset                   = assets.NewAssets().Golang
+		configurableRouteServiceAsset = assets.NewAssets().ConfigurableRouteService
+	)
+
+	routeServiceUrl := func(routeServiceName string) string {
+		routeServiceUrl, err := url.Parse(helpers.AppUri(routeServiceName, "/"))
+		Expect(err).NotTo(HaveOccurred())
+		routeServiceUrl.Scheme = "https"
+		return routeServiceUrl.String()
+	}
+
+	expectRoutedThrough := func(routeServiceName string) {
+		Eventually(func() []routeServiceRequest {
+			helpers.CurlAppRoot(appName)
+			return routeServiceRequests(routeServiceName)
+		}, DEFAULT_TIMEOUT).ShouldNot(BeEmpty())
+	}
+
+	BeforeEach(func() {
+		serviceInstanceName = generator.PrefixedRandomName("RATS-SERVICE-")
+		appName = GenerateAppName()
+		routeServiceNames = []string{GenerateAppName(), GenerateAppName()}
+
+		PushAppNoStart(appName, golangAsset, config.GoBuildpackName, config.AppsDomain, CF_PUSH_TIMEOUT)
+		EnableDiego(appName, DEFAULT_TIMEOUT)
+		StartApp(appName, CF_PUSH_TIMEOUT)
+
+		for _, routeServiceName := range routeServiceNames {
+			PushApp(routeServiceName, configurableRouteServiceAsset, config.GoBuildpackName, config.AppsDomain, CF_PUSH_TIMEOUT)
+		}
+	})
+
+	AfterEach(func() {
+		AppReport(appName, DEFAULT_TIMEOUT)
+		for _, routeServiceName := range routeServiceNames {
+			AppReport(routeServiceName, DEFAULT_TIMEOUT)
+		}
+
+		unbindRouteFromService(appName, serviceInstanceName)
+		deleteServiceInstance(serviceInstanceName)
+		DeleteApp(appName, DEFAULT_TIMEOUT)
+		for _, routeServiceName := range routeServiceNames {
+			DeleteApp(routeServiceName, DEFAULT_TIMEOUT)
+		}
+	})
+
+	It("routes requests to bound routes through the route service, and through the updated route service once the app is restaged", func() {
+		Expect(cf.Cf("create-user-provided-service", serviceInstanceName, "-r", routeServiceUrl(routeServiceNames[0])).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		bindRouteToService(appName, serviceInstanceName)
+
+		Expect(getRouteServiceUrl(serviceInstanceName)).To(Equal(routeServiceUrl(routeServiceNames[0])))
+		expectRoutedThrough(routeServiceNames[0])
+
+		Expect(cf.Cf("update-user-provided-service", serviceInstanceName, "-r", routeServiceUrl(routeServiceNames[1])).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("restage", appName).Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+
+		Expect(getRouteServiceUrl(serviceInstanceName)).To(Equal(routeServiceUrl(routeServiceNames[1])))
+		expectRoutedThrough(routeServiceNames[1])
+	})
+})
+
+// getRouteServiceUrl reads the route service URL of a user-provided service
+// instance from the v2 API.
+func getRouteServiceUrl(serviceInstanceName string) string {
+	guid := cf.Cf("service", serviceInstanceName, "--guid").Wait(DEFAULT_TIMEOUT)
+	Expect(guid).To(Exit(0))
+
+	session := cf.Cf("curl", fmt.Sprintf("/v2/user_provided_service_instances/%s", strings.TrimSpace(string(guid.Out.Contents())))).Wait(DEFAULT_TIMEOUT)
+	Expect(session).To(Exit(0))
+
+	var instance struct {
+		Entity struct {
+			RouteServiceUrl string `json:"route_service_url"`
+		} `json:"entity"`
+	}
+	Expect(json.Unmarshal(session.Out.Contents(), &instance)).To(Succeed())
+	return instance.Entity.RouteServiceUrl
+}
@@ -0,0 +1,181 @@
+package routing
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/cf-routing-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/runner"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("HTTP streaming", func() {
+	var (
+		appName           string
+		streamingAppAsset = assets.NewAssets().StreamingApp
+		tmpdir            string
+	)
+
+	BeforeEach(func() {
+		appName = GenerateAppName()
+		PushApp(appName, streamingAppAsset, config.GoBuildpackName, config.AppsDomain, CF_PUSH_TIMEOUT)
+
+		var err error
+		tmpdir, err = ioutil.TempDir("", "http-streaming")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		AppReport(appName, DEFAULT_TIMEOUT)
+		DeleteApp(appName, DEFAULT_TIMEOUT)
+
+		os.RemoveAll(tmpdir)
+	})
+
+	Context("when the app responds with chunked transfer encoding", func() {
+		It("delivers every chunk intact", func() {
+			kbytes := 512
+			headers, body := curlAppToFiles(tmpdir, appName, fmt.Sprintf("/chunked/%d", kbytes), DEFAULT_TIMEOUT)
+
+			Expect(headers.Get("Transfer-Encoding")).To(Equal("chunked"))
+			Expect(headers.Get("Content-Length")).To(BeEmpty())
+			Expect(body).To(HaveLen(kbytes * 1024))
+			Expect(sha256Hex(body)).To(Equal(headers.Get("X-Sha256")))
+		})
+	})
+
+	Context("when the app streams server-sent events", func() {
+		It("forwards each event as it is produced", func() {
+			eventCount := 10
+			curl := runner.Curl("-N", helpers.AppUri(appName, fmt.Sprintf("/sse/%d", eventCount)))
+
+			By("receiving the first event before the stream has finished")
+			Eventually(curl.Out, DEFAULT_TIMEOUT).Should(Say("data: "))
+			Expect(curl.ExitCode()).To(Equal(-1), "expected the event stream to still be open")
+
+			By("receiving the rest of the events")
+			Eventually(curl, DEFAULT_TIMEOUT+time.Duration(eventCount)*time.Second).Should(Exit(0))
+
+			var data []string
+			var expectedChecksum string
+			for _, event := range strings.Split(strings.TrimSpace(string(curl.Out.Contents())), "\n\n") {
+				fields := parseEvent(event)
+				if fields["event"] == "checksum" {
+					expectedChecksum = fields["data"]
+				} else {
+					data = append(data, fields["data"])
+				}
+			}
+
+			Expect(data).To(HaveLen(eventCount))
+			Expect(sha256Hex([]byte(strings.Join(data, "")))).To(Equal(expectedChecksum))
+		})
+	})
+
+	Context("when the app holds a request open for longer than the default curl timeout", func() {
+		It("returns the long-polled response intact", func() {
+			pollDuration := helpers.CURL_TIMEOUT + 15*time.Second
+
+			startTime := time.Now()
+			headers, body := curlAppToFiles(tmpdir, appName, fmt.Sprintf("/longpoll/%d", int(pollDuration.Seconds())), LONG_CURL_TIMEOUT)
+			Expect(time.Since(startTime)).To(BeNumerically(">=", pollDuration))
+
+			Expect(body).To(HaveLen(1024))
+			Expect(sha256Hex(body)).To(Equal(headers.Get("X-Sha256")))
+		})
+	})
+
+	Context("when a request body larger than 1MB is uploaded", func() {
+		It("delivers the whole body to the app", func() {
+			payload := make([]byte, 5*1024*1024)
+			_, err := rand.Read(payload)
+			Expect(err).NotTo(HaveOccurred())
+
+			payloadPath := filepath.Join(tmpdir, "payload")
+			Expect(ioutil.WriteFile(payloadPath, payload, 0644)).To(Succeed())
+
+			// Disable "Expect: 100-continue" so that the body is sent straight away
+			response := helpers.CurlAppWithTimeout(appName, "/upload", LONG_CURL_TIMEOUT,
+				"-X", "POST",
+				"-H", "Content-Type: application/octet-stream",
+				"-H", "Expect:",
+				"--data-binary", "@"+payloadPath,
+			)
+
+			var upload struct {
+				Bytes  int    `json:"bytes"`
+				Sha256 string `json:"sha256"`
+			}
+			Expect(json.Unmarshal([]byte(response), &upload)).To(Succeed())
+			Expect(upload.Bytes).To(Equal(len(payload)))
+			Expect(upload.Sha256).To(Equal(sha256Hex(payload)))
+		})
+	})
+})
+
+// curlAppToFiles writes the response headers and body to files in dir rather
+// than stdout, so that binary bodies are not mangled or dumped into the logs.
+func curlAppToFiles(dir, appName, path string, timeout time.Duration, args ...string) (http.Header, []byte) {
+	headerFile := filepath.Join(dir, "headers")
+	bodyFile := filepath.Join(dir, "body")
+
+	curlArgs := append([]string{helpers.AppUri(appName, path), "-D", headerFile, "-o", bodyFile}, args...)
+	Expect(runner.Curl(curlArgs...).Wait(timeout)).To(Exit(0))
+
+	rawHeaders, err := ioutil.ReadFile(headerFile)
+	Expect(err).NotTo(HaveOccurred())
+	body, err := ioutil.ReadFile(bodyFile)
+	Expect(err).NotTo(HaveOccurred())
+
+	return parseHeaders(rawHeaders), body
+}
+
+// parseHeaders returns the headers of the final response in a curl header
+// dump, skipping any interim "100 Continue" responses.
+func parseHeaders(rawHeaders []byte) http.Header {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(rawHeaders)))
+	for {
+		statusLine, err := reader.ReadLine()
+		Expect(err).NotTo(HaveOccurred())
+
+		header, err := reader.ReadMIMEHeader()
+		Expect(err).NotTo(HaveOccurred())
+
+		if !strings.Contains(statusLine, " 100 ") {
+			return http.Header(header)
+		}
+	}
+}
+
+func parseEvent(event string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(event, "\n") {
+		parts := strings.SplitN(line, ": ", 2)
+		if len(parts) == 2 {
+			fields[parts[0]] = parts[1]
+		}
+	}
+	return fields
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,113 @@
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+
+	. "github.com/cloudfoundry-incubator/cf-routing-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/runner"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+const internalAppPort = 8080
+
+type doraCurlResponse struct {
+	Stdout     string
+	Stderr     string
+	ReturnCode int `json:"return_code"`
+}
+
+var _ = Describe(deaUnsupportedTag+"Internal Routes", func() {
+	BeforeEach(func() {
+		if !routingConfig.IncludeInternalRoutes {
+			Skip("include_internal_routes is not set")
+		}
+	})
+
+	Context("when an app is mapped to a route on the internal domain", func() {
+		var (
+			serverAppName    string
+			clientAppName    string
+			internalHostname string
+			internalHost     string
+			policy           string
+			doraAsset        = assets.NewAssets().Dora
+		)
+
+		BeforeEach(func() {
+			serverAppName = GenerateAppName()
+			clientAppName = GenerateAppName()
+
+			for _, appName := range []string{serverAppName, clientAppName} {
+				PushAppNoStart(appName, doraAsset, config.RubyBuildpackName, config.AppsDomain, CF_PUSH_TIMEOUT)
+				EnableDiego(appName, DEFAULT_TIMEOUT)
+				StartApp(appName, APP_START_TIMEOUT)
+			}
+
+			internalHostname = generator.PrefixedRandomName("RATS-INTERNAL-")
+			internalHost = internalHostname + "." + routingConfig.InternalDomain
+			MapRouteToApp(serverAppName, routingConfig.InternalDomain, internalHostname, "", DEFAULT_TIMEOUT)
+
+			policy = fmt.Sprintf(`{"policies":[{"source":{"id":"%s"},"destination":{"id":"%s","protocol":"tcp","ports":{"start":%d,"end":%d}}}]}`,
+				GetAppGuid(clientAppName, DEFAULT_TIMEOUT),
+				GetAppGuid(serverAppName, DEFAULT_TIMEOUT),
+				internalAppPort, internalAppPort,
+			)
+		})
+
+		AfterEach(func() {
+			AppReport(serverAppName, DEFAULT_TIMEOUT)
+			AppReport(clientAppName, DEFAULT_TIMEOUT)
+
+			Expect(cf.Cf("curl", "/networking/v1/external/policies/delete", "-X", "POST", "-d", policy).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+			DeleteApp(serverAppName, DEFAULT_TIMEOUT)
+			DeleteApp(clientAppName, DEFAULT_TIMEOUT)
+		})
+
+		It("is reachable from other apps on the policy-allowed port, but not through the gorouter", func() {
+			By("resolving the internal hostname from inside another app's container")
+			var response doraCurlResponse
+			Eventually(func() string {
+				response = curlFromApp(clientAppName, internalHost, internalAppPort)
+				return response.Stderr
+			}, DEFAULT_TIMEOUT).Should(MatchRegexp(`Trying \d+\.\d+\.\d+\.\d+`))
+
+			By("denying traffic until a network policy allows it")
+			Expect(response.ReturnCode).NotTo(Equal(0))
+
+			Expect(cf.Cf("curl", "/networking/v1/external/policies", "-X", "POST", "-d", policy).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+			Eventually(func() string {
+				return curlFromApp(clientAppName, internalHost, internalAppPort).Stdout
+			}, DEFAULT_TIMEOUT).Should(ContainSubstring("Hi, I'm Dora!"))
+
+			By("failing to reach the internal hostname from outside the platform")
+			Expect(runner.Curl(config.Protocol() + internalHost).Wait(DEFAULT_TIMEOUT)).NotTo(Exit(0))
+
+			By("not registering the internal hostname with the gorouter")
+			curl := runner.Curl(helpers.AppUri(serverAppName, "/"),
+				"-H", "Host: "+internalHost,
+				"-o", "/dev/null",
+				"-w", "%{http_code}",
+			).Wait(DEFAULT_TIMEOUT)
+			Expect(curl).To(Exit(0))
+			Expect(string(curl.Out.Contents())).To(Equal("404"))
+		})
+	})
+})
+
+// curlFromApp uses Dora's /curl endpoint to make a request from inside the
+// app's container.
+func curlFromApp(appName, host string, port int) doraCurlResponse {
+	var response doraCurlResponse
+	err := json.Unmarshal([]byte(helpers.CurlApp(appName, fmt.Sprintf("/curl/%s/%d", host, port))), &response)
+	Expect(err).NotTo(HaveOccurred())
+	return response
+}
@@ -0,0 +1,146 @@
+package routing
+
+import (
+	"fmt"
+	"regexp"
+
+	. "github.com/cloudfoundry-incubator/cf-routing-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/runner"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/context_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Private Domains", func() {
+	var (
+		domainName        string
+		orgName           string
+		spaceName         string
+		otherContext      *context_helpers.SecondaryContext
+		otherOrgName      string
+		otherSpaceName    string
+		helloRoutingAsset = assets.NewAssets().HelloRouting
+	)
+
+	curlRoute := func(hostname, path string) string {
+		curl := runner.Curl(config.Protocol() + hostname + "." + domainName + path).Wait(DEFAULT_TIMEOUT)
+		Expect(curl).To(Exit(0))
+		return string(curl.Out.Contents())
+	}
+
+	BeforeEach(func() {
+		orgName = context.RegularUserContext().Org
+		spaceName = context.RegularUserContext().Space
+
+		otherContext = context_helpers.NewSecondaryContext(config)
+		otherContext.Setup()
+		otherOrgName = otherContext.RegularUserContext().Org
+		otherSpaceName = otherContext.RegularUserContext().Space
+
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			Expect(cf.Cf("set-org-role", context.RegularUserContext().Username, orgName, "OrgManager").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		})
+
+		By("creating the private domain as an org manager")
+		domainName = generator.RandomName() + "." + config.AppsDomain
+		Expect(cf.Cf("create-domain", orgName, domainName).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+	})
+
+	AfterEach(func() {
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			Expect(cf.Cf("unset-org-role", context.RegularUserContext().Username, orgName, "OrgManager").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		})
+
+		otherContext.Teardown()
+
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			Expect(cf.Cf("target", "-o", orgName).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			Expect(cf.Cf("delete-domain", domainName, "-f").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		})
+	})
+
+	It("routes to apps in the owning org", func() {
+		appName := GenerateAppName()
+		PushApp(appName, helloRoutingAsset, config.RubyBuildpackName, domainName, CF_PUSH_TIMEOUT)
+		defer func() {
+			AppReport(appName, DEFAULT_TIMEOUT)
+			DeleteApp(appName, DEFAULT_TIMEOUT)
+		}()
+
+		Eventually(func() string {
+			return curlRoute(appName, "/")
+		}, DEFAULT_TIMEOUT).Should(ContainSubstring(appName))
+	})
+
+	Context("when the private domain is not shared with another org", func() {
+		It("rejects routes on the domain in that org", func() {
+			hostname := generator.PrefixedRandomName("RATS-HOST-")
+
+			cf.AsUser(otherContext.RegularUserContext(), DEFAULT_TIMEOUT, func() {
+				session := cf.Cf("create-route", otherSpaceName, domainName, "--hostname", hostname).Wait(DEFAULT_TIMEOUT)
+				Expect(session).To(Exit(1))
+				Expect(session.Out).To(Say("Domain %s not found", regexp.QuoteMeta(domainName)))
+			})
+
+			cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+				routes := cf.Cf("curl", fmt.Sprintf("/v2/routes?q=host:%s", hostname)).Wait(DEFAULT_TIMEOUT)
+				Expect(routes).To(Exit(0))
+				Expect(routes.Out).To(Say(`"total_results": 0`))
+			})
+		})
+	})
+
+	Context("when the private domain is shared with another org", func() {
+		BeforeEach(func() {
+			cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+				Expect(cf.Cf("share-private-domain", otherOrgName, domainName).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			})
+		})
+
+		It("lets both orgs create routes on the domain, but not for the same hostname", func() {
+			hostname := generator.PrefixedRandomName("RATS-HOST-")
+			otherHostname := generator.PrefixedRandomName("RATS-HOST-")
+
+			CreateRoute(hostname, "", spaceName, domainName, DEFAULT_TIMEOUT)
+
+			cf.AsUser(otherContext.RegularUserContext(), DEFAULT_TIMEOUT, func() {
+				session := cf.Cf("create-route", otherSpaceName, domainName, "--hostname", hostname).Wait(DEFAULT_TIMEOUT)
+				Expect(session).To(Exit(1))
+				Expect(session.Out).To(Say("taken"))
+
+				CreateRoute(otherHostname, "", otherSpaceName, domainName, DEFAULT_TIMEOUT)
+			})
+
+			DeleteRoute(hostname, "", domainName, DEFAULT_TIMEOUT)
+		})
+
+		It("routes to apps in either org", func() {
+			appName := GenerateAppName()
+			otherAppName := GenerateAppName()
+
+			PushApp(appName, helloRoutingAsset, config.RubyBuildpackName, domainName, CF_PUSH_TIMEOUT)
+			defer func() {
+				AppReport(appName, DEFAULT_TIMEOUT)
+				DeleteApp(appName, DEFAULT_TIMEOUT)
+			}()
+
+			// The other org, and everything in it, is deleted on teardown
+			cf.AsUser(otherContext.RegularUserContext(), DEFAULT_TIMEOUT, func() {
+				PushApp(otherAppName, helloRoutingAsset, config.RubyBuildpackName, domainName, CF_PUSH_TIMEOUT)
+			})
+
+			Eventually(func() string {
+				return curlRoute(appName, "/")
+			}, DEFAULT_TIMEOUT).Should(ContainSubstring(appName))
+
+			Eventually(func() string {
+				return curlRoute(otherAppName, "/")
+			}, DEFAULT_TIMEOUT).Should(ContainSubstring(otherAppName))
+		})
+	})
+})
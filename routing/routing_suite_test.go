@@ -12,19 +12,44 @@ import (
 
 const deaUnsupportedTag = "{NO_DEA_SUPPORT} "
 
+// routingSuiteConfig adds the settings that only the routing suite reads to the
+// shared integration config.
+type routingSuiteConfig struct {
+	helpers.Config
+
+	IncludeInternalRoutes bool   `json:"include_internal_routes"`
+	InternalDomain        string `json:"internal_domain"`
+}
+
+func loadRoutingConfig() routingSuiteConfig {
+	var suiteConfig routingSuiteConfig
+	err := helpers.Load(helpers.ConfigPath(), &suiteConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	if suiteConfig.InternalDomain == "" {
+		suiteConfig.InternalDomain = "apps.internal"
+	}
+	return suiteConfig
+}
+
 var (
 	DEFAULT_TIMEOUT   = 1 * time.Minute
 	CF_PUSH_TIMEOUT   = 2 * time.Minute
 	APP_START_TIMEOUT = 2 * time.Minute
+	LONG_CURL_TIMEOUT = 2 * time.Minute
 
-	context helpers.SuiteContext
-	config  helpers.Config
+	context       helpers.SuiteContext
+	config        helpers.Config
+	routingConfig routingSuiteConfig
 )
 
 func TestRouting(t *testing.T) {
 	RegisterFailHandler(Fail)
 
 	config = helpers.LoadConfig()
+	routingConfig = loadRoutingConfig()
 
 	if config.DefaultTimeout > 0 {
 		DEFAULT_TIMEOUT = config.DefaultTimeout * time.Second
@@ -34,6 +59,10 @@ func TestRouting(t *testing.T) {
 		CF_PUSH_TIMEOUT = config.CfPushTimeout * time.Second
 	}
 
+	if config.LongCurlTimeout > 0 {
+		LONG_CURL_TIMEOUT = config.LongCurlTimeout * time.Second
+	}
+
 	componentName := "Routing"
 
 	rs := []Reporter{}
@@ -0,0 +1,181 @@
+package services_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/services"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+type ServiceBinding struct {
+	Metadata struct {
+		Guid string `json:"guid"`
+	} `json:"metadata"`
+	Entity struct {
+		LastOperation LastOperation `json:"last_operation"`
+	} `json:"entity"`
+	ErrorCode string `json:"error_code"`
+}
+
+var _ = Describe("Asynchronous service bindings", func() {
+	var (
+		broker       ServiceBroker
+		appName      string
+		appGuid      string
+		instanceGuid string
+		password     string
+
+		ASYNC_OPERATION_TIMEOUT       = 2 * time.Minute
+		ASYNC_OPERATION_POLL_INTERVAL = 5 * time.Second
+	)
+
+	// The CLI does not bind asynchronously, so bindings are created and
+	// deleted through the v2 API.
+	createBinding := func() string {
+		body := fmt.Sprintf(`{"app_guid":"%s","service_instance_guid":"%s"}`, appGuid, instanceGuid)
+		session := cf.Cf("curl", "/v2/service_bindings?accepts_incomplete=true", "-X", "POST", "-d", body).Wait(DEFAULT_TIMEOUT)
+		Expect(session).To(Exit(0))
+
+		var binding ServiceBinding
+		Expect(json.Unmarshal(session.Out.Contents(), &binding)).To(Succeed())
+		Expect(binding.Metadata.Guid).NotTo(BeEmpty(), string(session.Out.Contents()))
+		return binding.Metadata.Guid
+	}
+
+	deleteBinding := func(bindingGuid string) string {
+		session := cf.Cf("curl", fmt.Sprintf("/v2/service_bindings/%s?accepts_incomplete=true", bindingGuid), "-X", "DELETE").Wait(DEFAULT_TIMEOUT)
+		Expect(session).To(Exit(0))
+		return string(session.Out.Contents())
+	}
+
+	getBinding := func(bindingGuid string) ServiceBinding {
+		session := cf.Cf("curl", fmt.Sprintf("/v2/service_bindings/%s", bindingGuid)).Wait(DEFAULT_TIMEOUT)
+		Expect(session).To(Exit(0))
+
+		var binding ServiceBinding
+		Expect(json.Unmarshal(session.Out.Contents(), &binding)).To(Succeed())
+		return binding
+	}
+
+	getBindingLastOperation := func(bindingGuid string) LastOperation {
+		binding := getBinding(bindingGuid)
+		Expect(binding.ErrorCode).To(BeEmpty())
+		return binding.Entity.LastOperation
+	}
+
+	bindingPath := func(bindingGuid string) string {
+		return fmt.Sprintf("/v2/service_instances/%s/service_bindings/%s", instanceGuid, bindingGuid)
+	}
+
+	BeforeEach(func() {
+		broker = NewServiceBroker(
+			generator.PrefixedRandomName("abnd-brkr-"),
+			assets.NewAssets().GoServiceBroker,
+			context,
+		)
+		broker.Push()
+		broker.Configure()
+		broker.Create()
+		broker.PublicizePlans()
+
+		password = generator.RandomName()
+		broker.OnBind(broker.SyncPlans[0].ID).RespondWith(202, map[string]string{})
+		broker.OnGetBinding(broker.SyncPlans[0].ID).RespondWith(200, map[string]interface{}{
+			"credentials": map[string]string{"password": password},
+		})
+
+		instanceName := generator.PrefixedRandomName("abnd-")
+		createService := cf.Cf("create-service", broker.Service.Name, broker.SyncPlans[0].Name, instanceName).Wait(DEFAULT_TIMEOUT)
+		Expect(createService).To(Exit(0))
+		instanceGuid = getGuid("service", instanceName)
+
+		appName = generator.PrefixedRandomName("CATS-APP-abnd-")
+		createApp := cf.Cf("push", appName, "--no-start", "-b", config.RubyBuildpackName, "-m", DEFAULT_MEMORY_LIMIT, "-p", assets.NewAssets().Dora, "-d", config.AppsDomain).Wait(DEFAULT_TIMEOUT)
+		Expect(createApp).To(Exit(0), "failed creating app")
+		appGuid = getGuid("app", appName)
+	})
+
+	AfterEach(func() {
+		app_helpers.AppReport(broker.Name, DEFAULT_TIMEOUT)
+
+		Expect(cf.Cf("delete", appName, "-f", "-r").Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+		broker.Destroy()
+	})
+
+	It("gives the app the binding's credentials only once the binding succeeds", func() {
+		broker.OnBindingLastOperation(broker.SyncPlans[0].ID).Sequence(LastOperationState("in progress", "binding"))
+		bindingGuid := createBinding()
+
+		lastOperation := getBindingLastOperation(bindingGuid)
+		Expect(lastOperation.Type).To(Equal("create"))
+		Expect(lastOperation.State).To(Equal("in progress"))
+		Expect(getAppServices(appGuid)).NotTo(ContainSubstring(password))
+
+		broker.OnBindingLastOperation(broker.SyncPlans[0].ID).Sequence(LastOperationState("succeeded", "bound"))
+		Eventually(func() string {
+			return getBindingLastOperation(bindingGuid).State
+		}, ASYNC_OPERATION_TIMEOUT, ASYNC_OPERATION_POLL_INTERVAL).Should(Equal("succeeded"))
+
+		Expect(getAppServices(appGuid)).To(ContainSubstring(password))
+	})
+
+	It("shows the broker's description when an asynchronous bind fails", func() {
+		broker.OnBindingLastOperation(broker.SyncPlans[0].ID).Sequence(
+			LastOperationState("in progress", "binding"),
+			LastOperationState("failed", "the credentials could not be created"),
+		)
+		bindingGuid := createBinding()
+
+		Eventually(func() string {
+			return getBindingLastOperation(bindingGuid).State
+		}, ASYNC_OPERATION_TIMEOUT, ASYNC_OPERATION_POLL_INTERVAL).Should(Equal("failed"))
+
+		Expect(getBindingLastOperation(bindingGuid)).To(Equal(LastOperation{
+			Type:        "create",
+			State:       "failed",
+			Description: "the credentials could not be created",
+		}))
+		Expect(getAppServices(appGuid)).NotTo(ContainSubstring(password))
+	})
+
+	It("rejects unbinding while the bind is in progress, and unbinds asynchronously once it has succeeded", func() {
+		broker.OnBindingLastOperation(broker.SyncPlans[0].ID).Sequence(LastOperationState("in progress", "binding"))
+		bindingGuid := createBinding()
+
+		Expect(deleteBinding(bindingGuid)).To(ContainSubstring("in progress"))
+		Expect(broker.GetRequestsTo("DELETE", bindingPath(bindingGuid))).To(BeEmpty())
+		Expect(getBindingLastOperation(bindingGuid).State).To(Equal("in progress"))
+
+		broker.OnBindingLastOperation(broker.SyncPlans[0].ID).Sequence(LastOperationState("succeeded", "bound"))
+		Eventually(func() string {
+			return getBindingLastOperation(bindingGuid).State
+		}, ASYNC_OPERATION_TIMEOUT, ASYNC_OPERATION_POLL_INTERVAL).Should(Equal("succeeded"))
+
+		By("unbinding asynchronously")
+		broker.OnUnbind(broker.SyncPlans[0].ID).RespondWith(202, map[string]string{})
+		broker.OnBindingLastOperation(broker.SyncPlans[0].ID).Sequence(
+			LastOperationState("in progress", "unbinding"),
+			LastOperationState("succeeded", "unbound"),
+		)
+		deleteBinding(bindingGuid)
+		Expect(broker.GetRequestsTo("DELETE", bindingPath(bindingGuid))).To(HaveLen(1))
+
+		lastOperation := getBindingLastOperation(bindingGuid)
+		Expect(lastOperation.Type).To(Equal("delete"))
+		Expect(lastOperation.State).To(Equal("in progress"))
+
+		Eventually(func() string {
+			return getBinding(bindingGuid).ErrorCode
+		}, ASYNC_OPERATION_TIMEOUT, ASYNC_OPERATION_POLL_INTERVAL).Should(Equal("CF-ServiceBindingNotFound"))
+		Expect(getAppServices(appGuid)).NotTo(ContainSubstring(password))
+	})
+})
@@ -0,0 +1,183 @@
+package services_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/services"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Service broker errors", func() {
+	var (
+		broker       ServiceBroker
+		instanceName string
+
+		ASYNC_OPERATION_TIMEOUT       = 2 * time.Minute
+		ASYNC_OPERATION_POLL_INTERVAL = 5 * time.Second
+	)
+
+	getServiceInstances := func(instanceName string) []Resource {
+		session := cf.Cf("curl", fmt.Sprintf("/v2/service_instances?q=name:%s", instanceName)).Wait(DEFAULT_TIMEOUT)
+		Expect(session).To(Exit(0))
+
+		var response Response
+		Expect(json.Unmarshal(session.Out.Contents(), &response)).To(Succeed())
+		return response.Resources
+	}
+
+	getLastOperation := func(instanceName string) LastOperation {
+		instances := getServiceInstances(instanceName)
+		Expect(instances).To(HaveLen(1))
+		return instances[0].Entity.LastOperation
+	}
+
+	expectNoServiceInstance := func(instanceName string) {
+		Expect(cf.Cf("service", instanceName).Wait(DEFAULT_TIMEOUT)).To(Say("not found"))
+		Expect(getServiceInstances(instanceName)).To(BeEmpty())
+	}
+
+	// Cloud Controller cannot know whether the broker created an instance
+	// when provisioning fails, so it asks the broker to delete it.
+	expectOrphanMitigation := func() {
+		provisionPath := regexp.MustCompile(`^/v2/service_instances/[^/]+$`)
+
+		var provisionRequests []BrokerRequest
+		for _, request := range broker.GetRequests() {
+			if request.Method == "PUT" && provisionPath.MatchString(request.Path) {
+				provisionRequests = append(provisionRequests, request)
+			}
+		}
+		Expect(provisionRequests).To(HaveLen(1))
+
+		Eventually(func() []BrokerRequest {
+			return broker.GetRequestsTo("DELETE", provisionRequests[0].Path)
+		}, ASYNC_OPERATION_TIMEOUT, ASYNC_OPERATION_POLL_INTERVAL).ShouldNot(BeEmpty())
+	}
+
+	BeforeEach(func() {
+		broker = NewServiceBroker(
+			generator.PrefixedRandomName("err-brkr-"),
+			assets.NewAssets().GoServiceBroker,
+			context,
+		)
+		broker.Push()
+		broker.Configure()
+		broker.Create()
+		broker.PublicizePlans()
+
+		instanceName = generator.PrefixedRandomName("err-")
+	})
+
+	AfterEach(func() {
+		app_helpers.AppReport(broker.Name, DEFAULT_TIMEOUT)
+
+		broker.Destroy()
+	})
+
+	Context("when provisioning fails", func() {
+		It("shows the broker's error and deprovisions the orphaned instance", func() {
+			broker.OnProvision(broker.SyncPlans[0].ID).RespondWith(500, map[string]string{
+				"description": "the broker is broken",
+			})
+
+			createService := cf.Cf("create-service", broker.Service.Name, broker.SyncPlans[0].Name, instanceName).Wait(DEFAULT_TIMEOUT)
+			Expect(createService).To(Exit(1))
+			Expect(createService).To(Say("the broker is broken"))
+
+			expectNoServiceInstance(instanceName)
+			expectOrphanMitigation()
+		})
+
+		It("shows that the broker timed out and deprovisions the orphaned instance", func() {
+			brokerClientTimeout := time.Duration(servicesConfig.BrokerClientTimeoutSeconds) * time.Second
+			broker.OnProvision(broker.SyncPlans[0].ID).After(brokerClientTimeout + 10*time.Second)
+
+			createService := cf.Cf("create-service", broker.Service.Name, broker.SyncPlans[0].Name, instanceName).Wait(brokerClientTimeout + DEFAULT_TIMEOUT)
+			Expect(createService).To(Exit(1))
+			Expect(createService).To(Say("timed out"))
+
+			expectNoServiceInstance(instanceName)
+			expectOrphanMitigation()
+		})
+
+		It("shows the broker's description when an asynchronous provision fails", func() {
+			broker.OnLastOperation(broker.AsyncPlans[0].ID).Sequence(
+				LastOperationState("in progress", "creating the database"),
+				LastOperationState("failed", "the database could not be created"),
+			)
+
+			createService := cf.Cf("create-service", broker.Service.Name, broker.AsyncPlans[0].Name, instanceName).Wait(DEFAULT_TIMEOUT)
+			Expect(createService).To(Exit(0))
+			Expect(createService).To(Say("Create in progress."))
+
+			Eventually(func() *Session {
+				return cf.Cf("service", instanceName).Wait(DEFAULT_TIMEOUT)
+			}, ASYNC_OPERATION_TIMEOUT, ASYNC_OPERATION_POLL_INTERVAL).Should(Say("Status: create failed"))
+
+			serviceInfo := cf.Cf("service", instanceName).Wait(DEFAULT_TIMEOUT)
+			Expect(serviceInfo).To(Say("Message: the database could not be created"))
+
+			Expect(getLastOperation(instanceName)).To(Equal(LastOperation{
+				Type:        "create",
+				State:       "failed",
+				Description: "the database could not be created",
+			}))
+		})
+	})
+
+	Context("when there is a service instance", func() {
+		BeforeEach(func() {
+			createService := cf.Cf("create-service", broker.Service.Name, broker.SyncPlans[0].Name, instanceName).Wait(DEFAULT_TIMEOUT)
+			Expect(createService).To(Exit(0))
+		})
+
+		It("fails to update while the broker reports another operation in progress", func() {
+			broker.OnUpdate(broker.SyncPlans[1].ID).RespondWith(422, map[string]string{
+				"error":       "ConcurrencyError",
+				"description": "Another operation for this service instance is in progress",
+			})
+
+			updateService := cf.Cf("update-service", instanceName, "-p", broker.SyncPlans[1].Name).Wait(DEFAULT_TIMEOUT)
+			Expect(updateService).To(Exit(1))
+			Expect(strings.ToLower(string(updateService.Out.Contents()))).To(ContainSubstring("in progress"))
+
+			serviceInfo := cf.Cf("service", instanceName).Wait(DEFAULT_TIMEOUT)
+			Expect(serviceInfo).To(Say(fmt.Sprintf("Plan: %s", broker.SyncPlans[0].Name)))
+			Expect(serviceInfo).To(Say("Status: update failed"))
+
+			lastOperation := getLastOperation(instanceName)
+			Expect(lastOperation.Type).To(Equal("update"))
+			Expect(lastOperation.State).To(Equal("failed"))
+		})
+
+		It("fails to create a service key when the broker requires asynchronous bindings", func() {
+			broker.OnBind(broker.SyncPlans[0].ID).AsyncOnly()
+
+			keyName := generator.PrefixedRandomName("err-key-")
+			createKey := cf.Cf("create-service-key", instanceName, keyName).Wait(DEFAULT_TIMEOUT)
+			Expect(createKey).To(Exit(1))
+			Expect(createKey).To(Say("requires client support for asynchronous service operations"))
+
+			keyInfo := cf.Cf("service-key", instanceName, keyName).Wait(DEFAULT_TIMEOUT)
+			Expect(keyInfo).To(Say(fmt.Sprintf("No service key %s found for service instance %s", keyName, instanceName)))
+
+			session := cf.Cf("curl", fmt.Sprintf("/v2/service_keys?q=name:%s", keyName)).Wait(DEFAULT_TIMEOUT)
+			Expect(session).To(Exit(0))
+			var keys ServiceInstanceResponse
+			Expect(json.Unmarshal(session.Out.Contents(), &keys)).To(Succeed())
+			Expect(keys.Resources).To(BeEmpty())
+		})
+	})
+})
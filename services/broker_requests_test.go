@@ -0,0 +1,243 @@
+package services_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/services"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Requests to service brokers", func() {
+	var (
+		broker       ServiceBroker
+		instanceName string
+		params       = map[string]interface{}{"param1": "value"}
+	)
+
+	// The originating identity is "cloudfoundry <base64 encoded JSON>",
+	// identifying the user whose token is used by the CLI.
+	expectRequestFromCloudController := func(request BrokerRequest) {
+		Expect(request.Header("X-Broker-API-Version")).To(MatchRegexp(`^2\.\d+$`))
+
+		identity := strings.Fields(request.Header("X-Broker-API-Originating-Identity"))
+		Expect(identity).To(HaveLen(2))
+		Expect(identity[0]).To(Equal("cloudfoundry"))
+		decodedIdentity, err := base64.StdEncoding.DecodeString(identity[1])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decodedIdentity).To(MatchJSON(fmt.Sprintf(`{"user_id":"%s"}`, currentUserGuid())))
+	}
+
+	onlyRequestTo := func(method, path string) BrokerRequest {
+		requests := broker.GetRequestsTo(method, path)
+		Expect(requests).To(HaveLen(1), "expected a single %s request to %s", method, path)
+		expectRequestFromCloudController(requests[0])
+		return requests[0]
+	}
+
+	createService := func(planName string, args ...string) {
+		instanceName = generator.PrefixedRandomName("rqst-")
+		createService := cf.Cf(append([]string{"create-service", broker.Service.Name, planName, instanceName}, args...)...).Wait(DEFAULT_TIMEOUT)
+		Expect(createService).To(Exit(0))
+	}
+
+	BeforeEach(func() {
+		broker = NewServiceBroker(
+			generator.PrefixedRandomName("rqst-brkr-"),
+			assets.NewAssets().GoServiceBroker,
+			context,
+		)
+		broker.Push()
+		broker.Configure()
+		broker.Create()
+		broker.PublicizePlans()
+	})
+
+	AfterEach(func() {
+		app_helpers.AppReport(broker.Name, DEFAULT_TIMEOUT)
+
+		broker.Destroy()
+	})
+
+	It("provisions with the service, plan, parameters and the space the instance is created in", func() {
+		encodedParams, _ := json.Marshal(params)
+		createService(broker.SyncPlans[0].Name, "-c", string(encodedParams))
+
+		request := onlyRequestTo("PUT", "/v2/service_instances/"+getGuid("service", instanceName))
+		Expect(request.QueryValues().Get("accepts_incomplete")).To(Equal("true"))
+
+		orgGuid := getGuid("org", context.RegularUserContext().Org)
+		spaceGuid := getGuid("space", context.RegularUserContext().Space)
+		body := request.JSONBody()
+		Expect(body).To(HaveKeyWithValue("service_id", broker.Service.ID))
+		Expect(body).To(HaveKeyWithValue("plan_id", broker.SyncPlans[0].ID))
+		Expect(body).To(HaveKeyWithValue("organization_guid", orgGuid))
+		Expect(body).To(HaveKeyWithValue("space_guid", spaceGuid))
+		Expect(body).To(HaveKeyWithValue("parameters", params))
+		Expect(body).To(HaveKey("context"))
+		Expect(body["context"]).To(HaveKeyWithValue("platform", "cloudfoundry"))
+		Expect(body["context"]).To(HaveKeyWithValue("organization_guid", orgGuid))
+		Expect(body["context"]).To(HaveKeyWithValue("space_guid", spaceGuid))
+	})
+
+	Context("when there is a service instance", func() {
+		var instanceGuid string
+
+		BeforeEach(func() {
+			createService(broker.SyncPlans[0].Name)
+			instanceGuid = getGuid("service", instanceName)
+		})
+
+		It("updates with the new plan, parameters and the previous values", func() {
+			encodedParams, _ := json.Marshal(params)
+			updateService := cf.Cf("update-service", instanceName, "-p", broker.SyncPlans[1].Name, "-c", string(encodedParams)).Wait(DEFAULT_TIMEOUT)
+			Expect(updateService).To(Exit(0))
+
+			request := onlyRequestTo("PATCH", "/v2/service_instances/"+instanceGuid)
+			Expect(request.QueryValues().Get("accepts_incomplete")).To(Equal("true"))
+
+			body := request.JSONBody()
+			Expect(body).To(HaveKeyWithValue("service_id", broker.Service.ID))
+			Expect(body).To(HaveKeyWithValue("plan_id", broker.SyncPlans[1].ID))
+			Expect(body).To(HaveKeyWithValue("parameters", params))
+			Expect(body).To(HaveKey("previous_values"))
+			Expect(body["previous_values"]).To(HaveKeyWithValue("plan_id", broker.SyncPlans[0].ID))
+			Expect(body["previous_values"]).To(HaveKeyWithValue("service_id", broker.Service.ID))
+		})
+
+		It("deprovisions with the service and plan", func() {
+			Expect(cf.Cf("delete-service", instanceName, "-f").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+			request := onlyRequestTo("DELETE", "/v2/service_instances/"+instanceGuid)
+			Expect(request.QueryValues().Get("service_id")).To(Equal(broker.Service.ID))
+			Expect(request.QueryValues().Get("plan_id")).To(Equal(broker.SyncPlans[0].ID))
+			Expect(request.QueryValues().Get("accepts_incomplete")).To(Equal("true"))
+		})
+
+		Context("when there is an app", func() {
+			var appName, appGuid string
+
+			BeforeEach(func() {
+				appName = generator.PrefixedRandomName("CATS-APP-rqst-")
+				createApp := cf.Cf("push", appName, "--no-start", "-b", config.RubyBuildpackName, "-m", DEFAULT_MEMORY_LIMIT, "-p", assets.NewAssets().Dora, "-d", config.AppsDomain).Wait(DEFAULT_TIMEOUT)
+				Expect(createApp).To(Exit(0), "failed creating app")
+				appGuid = getGuid("app", appName)
+			})
+
+			AfterEach(func() {
+				Expect(cf.Cf("delete", appName, "-f", "-r").Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+			})
+
+			It("binds and unbinds with the service, plan and the app", func() {
+				encodedParams, _ := json.Marshal(params)
+				bindService := cf.Cf("bind-service", appName, instanceName, "-c", string(encodedParams)).Wait(DEFAULT_TIMEOUT)
+				Expect(bindService).To(Exit(0))
+
+				bindingGuid := getBindingGuid(appGuid)
+				bindingPath := fmt.Sprintf("/v2/service_instances/%s/service_bindings/%s", instanceGuid, bindingGuid)
+
+				body := onlyRequestTo("PUT", bindingPath).JSONBody()
+				Expect(body).To(HaveKeyWithValue("service_id", broker.Service.ID))
+				Expect(body).To(HaveKeyWithValue("plan_id", broker.SyncPlans[0].ID))
+				Expect(body).To(HaveKeyWithValue("app_guid", appGuid))
+				Expect(body).To(HaveKey("bind_resource"))
+				Expect(body["bind_resource"]).To(HaveKeyWithValue("app_guid", appGuid))
+				Expect(body).To(HaveKeyWithValue("parameters", params))
+
+				unbindService := cf.Cf("unbind-service", appName, instanceName).Wait(DEFAULT_TIMEOUT)
+				Expect(unbindService).To(Exit(0))
+
+				request := onlyRequestTo("DELETE", bindingPath)
+				Expect(request.QueryValues().Get("service_id")).To(Equal(broker.Service.ID))
+				Expect(request.QueryValues().Get("plan_id")).To(Equal(broker.SyncPlans[0].ID))
+			})
+		})
+
+		Context("when the service requires route forwarding and there is a route", func() {
+			var hostname string
+
+			// The Cloud Controller does not expose route bindings' guids,
+			// so their requests are found by the instance they are for.
+			onlyBindingRequest := func(method string) BrokerRequest {
+				requests := []BrokerRequest{}
+				for _, request := range broker.GetRequests() {
+					if request.Method == method && strings.HasPrefix(request.Path, "/v2/service_instances/"+instanceGuid+"/service_bindings/") {
+						requests = append(requests, request)
+					}
+				}
+				Expect(requests).To(HaveLen(1), "expected a single %s request to bind the route", method)
+				expectRequestFromCloudController(requests[0])
+				return requests[0]
+			}
+
+			BeforeEach(func() {
+				catalog := broker.GetCatalog()
+				service := catalog["services"].([]interface{})[0].(map[string]interface{})
+				service["requires"] = []string{"route_forwarding"}
+				broker.ServeCatalog(catalog)
+				broker.Update()
+
+				hostname = generator.PrefixedRandomName("rqst-route-")
+				createRoute := cf.Cf("create-route", context.RegularUserContext().Space, config.AppsDomain, "--hostname", hostname).Wait(DEFAULT_TIMEOUT)
+				Expect(createRoute).To(Exit(0))
+			})
+
+			AfterEach(func() {
+				Expect(cf.Cf("delete-route", config.AppsDomain, "--hostname", hostname, "-f").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			})
+
+			It("binds and unbinds with the service, plan and the route", func() {
+				bindRoute := cf.Cf("bind-route-service", config.AppsDomain, instanceName, "--hostname", hostname, "-f").Wait(DEFAULT_TIMEOUT)
+				Expect(bindRoute).To(Exit(0))
+
+				bindRequest := onlyBindingRequest("PUT")
+				body := bindRequest.JSONBody()
+				Expect(body).To(HaveKeyWithValue("service_id", broker.Service.ID))
+				Expect(body).To(HaveKeyWithValue("plan_id", broker.SyncPlans[0].ID))
+				Expect(body).NotTo(HaveKey("app_guid"))
+				Expect(body).To(HaveKey("bind_resource"))
+				Expect(body["bind_resource"]).To(HaveKeyWithValue("route", hostname+"."+config.AppsDomain))
+
+				unbindRoute := cf.Cf("unbind-route-service", config.AppsDomain, instanceName, "--hostname", hostname, "-f").Wait(DEFAULT_TIMEOUT)
+				Expect(unbindRoute).To(Exit(0))
+
+				request := onlyBindingRequest("DELETE")
+				Expect(request.Path).To(Equal(bindRequest.Path))
+				Expect(request.QueryValues().Get("service_id")).To(Equal(broker.Service.ID))
+				Expect(request.QueryValues().Get("plan_id")).To(Equal(broker.SyncPlans[0].ID))
+			})
+		})
+	})
+})
+
+// currentUserGuid reads the user_id claim from the CLI's access token.
+func currentUserGuid() string {
+	session := cf.Cf("oauth-token").Wait(DEFAULT_TIMEOUT)
+	Expect(session).To(Exit(0))
+
+	var token string
+	for _, field := range strings.Fields(string(session.Out.Contents())) {
+		if strings.Count(field, ".") == 2 {
+			token = field
+		}
+	}
+	Expect(token).NotTo(BeEmpty(), "no access token in %s", session.Out.Contents())
+
+	claims, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	Expect(err).NotTo(HaveOccurred())
+
+	var user struct {
+		UserId string `json:"user_id"`
+	}
+	Expect(json.Unmarshal(claims, &user)).To(Succeed())
+	return user.UserId
+}
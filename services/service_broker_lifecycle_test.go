@@ -18,9 +18,122 @@ import (
 	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/services"
 )
 
+// invalidCatalog is a change that makes the broker's catalog one the Cloud
+// Controller rejects with message.
+type invalidCatalog struct {
+	description string
+	invalidate  func(catalog map[string]interface{})
+	message     string
+}
+
 var _ = Describe("Service Broker Lifecycle", func() {
 	var broker ServiceBroker
 
+	catalogServices := func(catalog map[string]interface{}) []interface{} {
+		return catalog["services"].([]interface{})
+	}
+
+	catalogService := func(catalog map[string]interface{}) map[string]interface{} {
+		return catalogServices(catalog)[0].(map[string]interface{})
+	}
+
+	catalogPlan := func(catalog map[string]interface{}, index int) map[string]interface{} {
+		return catalogService(catalog)["plans"].([]interface{})[index].(map[string]interface{})
+	}
+
+	invalidCatalogs := []invalidCatalog{
+		{
+			description: "a plan id is not unique",
+			invalidate: func(catalog map[string]interface{}) {
+				catalogPlan(catalog, 1)["id"] = catalogPlan(catalog, 0)["id"]
+			},
+			message: "Plan ids must be unique",
+		},
+		{
+			description: "a service has no description",
+			invalidate: func(catalog map[string]interface{}) {
+				delete(catalogService(catalog), "description")
+			},
+			message: "Service description is required",
+		},
+		{
+			description: "a plan has no name",
+			invalidate: func(catalog map[string]interface{}) {
+				delete(catalogPlan(catalog, 0), "name")
+			},
+			message: "Plan name is required",
+		},
+		{
+			description: "two services have the same name",
+			invalidate: func(catalog map[string]interface{}) {
+				encoded, err := json.Marshal(catalogService(catalog))
+				Expect(err).NotTo(HaveOccurred())
+				var otherService map[string]interface{}
+				Expect(json.Unmarshal(encoded, &otherService)).To(Succeed())
+
+				otherService["id"] = generator.RandomName()
+				delete(otherService, "dashboard_client")
+				for _, plan := range otherService["plans"].([]interface{}) {
+					plan.(map[string]interface{})["id"] = generator.RandomName()
+				}
+				catalog["services"] = append(catalogServices(catalog), otherService)
+			},
+			message: "Service names must be unique within a broker",
+		},
+		{
+			description: "a dashboard client has no secret",
+			invalidate: func(catalog map[string]interface{}) {
+				delete(catalogService(catalog)["dashboard_client"].(map[string]interface{}), "secret")
+			},
+			message: "Service dashboard client secret is required",
+		},
+	}
+
+	// The CLI does not show the error code, so requests are repeated
+	// through the v2 API for it.
+	expectRejected := func(cliArgs []string, method, path, body, message string) {
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			session := cf.Cf(cliArgs...).Wait(DEFAULT_TIMEOUT)
+			Expect(session).To(Exit(1))
+			Expect(session.Out.Contents()).To(ContainSubstring(message))
+
+			session = cf.Cf("curl", path, "-X", method, "-d", body).Wait(DEFAULT_TIMEOUT)
+			Expect(session).To(Exit(0))
+
+			var ccError CloudControllerError
+			Expect(json.Unmarshal(session.Out.Contents(), &ccError)).To(Succeed())
+			Expect(ccError.ErrorCode).To(Equal("CF-ServiceBrokerCatalogInvalid"))
+			Expect(ccError.Description).To(ContainSubstring(message))
+		})
+	}
+
+	getBrokerGuids := func() []string {
+		guids := []string{}
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			session := cf.Cf("curl", fmt.Sprintf("/v2/service_brokers?q=name:%s", broker.Name)).Wait(DEFAULT_TIMEOUT)
+			Expect(session).To(Exit(0))
+
+			var brokers ServiceInstanceResponse
+			Expect(json.Unmarshal(session.Out.Contents(), &brokers)).To(Succeed())
+			for _, resource := range brokers.Resources {
+				guids = append(guids, resource.Metadata.Guid)
+			}
+		})
+		return guids
+	}
+
+	// getServices returns the services with the given name, with their
+	// plans, as the Cloud Controller has them.
+	getServices := func(serviceName string) []ServiceResponse {
+		var services ServicesResponse
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			session := cf.Cf("curl", fmt.Sprintf("/v2/services?q=label:%s&inline-relations-depth=1", serviceName)).Wait(DEFAULT_TIMEOUT)
+			Expect(session).To(Exit(0))
+			Expect(json.Unmarshal(session.Out.Contents(), &services)).To(Succeed())
+		})
+		return services.Resources
+	}
+
 	Describe("public brokers", func() {
 		var acls *Session
 		var output []byte
@@ -85,6 +198,58 @@ var _ = Describe("Service Broker Lifecycle", func() {
 				Expect(plans).NotTo(Say(broker.Service.Name))
 				Expect(plans).NotTo(Say(broker.Plans()[0].Name))
 			})
+
+			Context("when the new catalog is invalid", func() {
+				var oldPlanNames []string
+
+				BeforeEach(func() {
+					oldServiceName = broker.Service.Name
+					oldPlanNames = []string{}
+					for _, plan := range broker.Plans() {
+						oldPlanNames = append(oldPlanNames, plan.Name)
+					}
+				})
+
+				// The broker is destroyed by its previous service name.
+				AfterEach(func() {
+					broker.Service.Name = oldServiceName
+				})
+
+				for _, invalid := range invalidCatalogs {
+					invalid := invalid
+
+					It(fmt.Sprintf("is rejected, keeping the previous catalog, when %s", invalid.description), func() {
+						// Valid changes alongside the invalid one must not be
+						// persisted either.
+						broker.Service.Name = generator.PrefixedRandomName("pblc-brkr-")
+						broker.SyncPlans[0].Name = generator.PrefixedRandomName("pblc-brkr-")
+						broker.Configure()
+
+						catalog := broker.GetCatalog()
+						invalid.invalidate(catalog)
+						broker.ServeCatalog(catalog)
+
+						brokerGuids := getBrokerGuids()
+						Expect(brokerGuids).To(HaveLen(1))
+
+						expectRejected(
+							[]string{"update-service-broker", broker.Name, "username", "password", helpers.AppUri(broker.Name, "")},
+							"PUT", fmt.Sprintf("/v2/service_brokers/%s", brokerGuids[0]), "{}",
+							invalid.message,
+						)
+
+						Expect(getServices(broker.Service.Name)).To(BeEmpty())
+
+						services := getServices(oldServiceName)
+						Expect(services).To(HaveLen(1))
+						planNames := []string{}
+						for _, plan := range services[0].Entity.ServicePlans {
+							planNames = append(planNames, plan.Entity.Name)
+						}
+						Expect(planNames).To(ConsistOf(oldPlanNames))
+					})
+				}
+			})
 		})
 
 		Describe("service access", func() {
@@ -192,6 +357,52 @@ var _ = Describe("Service Broker Lifecycle", func() {
 		})
 	})
 
+	Describe("brokers with invalid catalogs", func() {
+		BeforeEach(func() {
+			broker = NewServiceBroker(
+				generator.PrefixedRandomName("ivld-brkr-"),
+				assets.NewAssets().ServiceBroker,
+				context,
+			)
+			cf.TargetSpace(context.RegularUserContext(), context.ShortTimeout())
+			broker.Push()
+			broker.Configure()
+		})
+
+		AfterEach(func() {
+			app_helpers.AppReport(broker.Name, DEFAULT_TIMEOUT)
+
+			Expect(cf.Cf("delete", broker.Name, "-f", "-r").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		})
+
+		for _, invalid := range invalidCatalogs {
+			invalid := invalid
+
+			It(fmt.Sprintf("cannot be created, persisting nothing, when %s", invalid.description), func() {
+				catalog := broker.GetCatalog()
+				invalid.invalidate(catalog)
+				broker.ServeCatalog(catalog)
+
+				body, err := json.Marshal(map[string]string{
+					"name":          broker.Name,
+					"broker_url":    helpers.AppUri(broker.Name, ""),
+					"auth_username": "username",
+					"auth_password": "password",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				expectRejected(
+					[]string{"create-service-broker", broker.Name, "username", "password", helpers.AppUri(broker.Name, "")},
+					"POST", "/v2/service_brokers", string(body),
+					invalid.message,
+				)
+
+				Expect(getBrokerGuids()).To(BeEmpty())
+				Expect(getServices(broker.Service.Name)).To(BeEmpty())
+			})
+		}
+	})
+
 	Describe("private brokers", func() {
 		BeforeEach(func() {
 			broker = NewServiceBroker(
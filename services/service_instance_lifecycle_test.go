@@ -19,7 +19,9 @@ import (
 )
 
 type LastOperation struct {
-	State string `json:"state"`
+	Type        string `json:"type"`
+	State       string `json:"state"`
+	Description string `json:"description"`
 }
 
 type Service struct {
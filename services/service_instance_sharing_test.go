@@ -0,0 +1,180 @@
+package services_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/context_helpers"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/services"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Service instance sharing", func() {
+	var (
+		broker            ServiceBroker
+		instanceName      string
+		instanceGuid      string
+		otherSpaceName    string
+		otherSpaceGuid    string
+		appName           string
+		appGuid           string
+		password          string
+		sharingWasEnabled bool
+	)
+
+	sharingEnabled := func() bool {
+		session := cf.Cf("curl", "/v2/config/feature_flags/service_instance_sharing").Wait(DEFAULT_TIMEOUT)
+		Expect(session).To(Exit(0))
+
+		var featureFlag struct {
+			Enabled bool `json:"enabled"`
+		}
+		Expect(json.Unmarshal(session.Out.Contents(), &featureFlag)).To(Succeed())
+		return featureFlag.Enabled
+	}
+
+	// The CLI cannot share service instances, so they are shared through the
+	// v3 API.
+	shareInstance := func() {
+		body := fmt.Sprintf(`{"data":[{"guid":"%s"}]}`, otherSpaceGuid)
+		session := cf.Cf("curl", fmt.Sprintf("/v3/service_instances/%s/relationships/shared_spaces", instanceGuid), "-X", "POST", "-d", body).Wait(DEFAULT_TIMEOUT)
+		Expect(session).To(Exit(0))
+		Expect(session.Out.Contents()).NotTo(ContainSubstring(`"errors"`))
+	}
+
+	unshareInstance := func() string {
+		session := cf.Cf("curl", fmt.Sprintf("/v3/service_instances/%s/relationships/shared_spaces/%s", instanceGuid, otherSpaceGuid), "-X", "DELETE").Wait(DEFAULT_TIMEOUT)
+		Expect(session).To(Exit(0))
+		return string(session.Out.Contents())
+	}
+
+	getSharedSpaceGuids := func() []string {
+		session := cf.Cf("curl", fmt.Sprintf("/v3/service_instances/%s/relationships/shared_spaces", instanceGuid)).Wait(DEFAULT_TIMEOUT)
+		Expect(session).To(Exit(0))
+
+		var sharedSpaces struct {
+			Data []struct {
+				Guid string `json:"guid"`
+			} `json:"data"`
+		}
+		Expect(json.Unmarshal(session.Out.Contents(), &sharedSpaces)).To(Succeed())
+
+		guids := []string{}
+		for _, space := range sharedSpaces.Data {
+			guids = append(guids, space.Guid)
+		}
+		return guids
+	}
+
+	bindAppInOtherSpace := func() {
+		Expect(cf.Cf("target", "-s", otherSpaceName).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+		appName = generator.PrefixedRandomName("CATS-APP-shr-")
+		createApp := cf.Cf("push", appName, "--no-start", "-b", config.RubyBuildpackName, "-m", DEFAULT_MEMORY_LIMIT, "-p", assets.NewAssets().Dora, "-d", config.AppsDomain).Wait(DEFAULT_TIMEOUT)
+		Expect(createApp).To(Exit(0), "failed creating app")
+		appGuid = getGuid("app", appName)
+
+		Expect(cf.Cf("bind-service", appName, instanceName).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+	}
+
+	BeforeEach(func() {
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			sharingWasEnabled = sharingEnabled()
+			Expect(cf.Cf("enable-feature-flag", "service_instance_sharing").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+
+			otherSpaceName = generator.PrefixedRandomName("CATS-SPACE-")
+			context_helpers.SetUpSpaceWithUserAccess(context, otherSpaceName)
+		})
+		otherSpaceGuid = getGuid("space", otherSpaceName)
+
+		broker = NewServiceBroker(
+			generator.PrefixedRandomName("shr-brkr-"),
+			assets.NewAssets().GoServiceBroker,
+			context,
+		)
+		broker.Push()
+		broker.Configure()
+		broker.Create()
+		broker.PublicizePlans()
+
+		password = generator.RandomName()
+		broker.OnBind(broker.SyncPlans[0].ID).RespondWith(201, map[string]interface{}{
+			"credentials": map[string]string{"password": password},
+		})
+
+		instanceName = generator.PrefixedRandomName("shr-")
+		createService := cf.Cf("create-service", broker.Service.Name, broker.SyncPlans[0].Name, instanceName).Wait(DEFAULT_TIMEOUT)
+		Expect(createService).To(Exit(0))
+		instanceGuid = getGuid("service", instanceName)
+	})
+
+	AfterEach(func() {
+		Expect(cf.Cf("target", "-s", context.RegularUserContext().Space).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		app_helpers.AppReport(broker.Name, DEFAULT_TIMEOUT)
+
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			context_helpers.DeleteSpace(context, otherSpaceName)
+		})
+		broker.Destroy()
+
+		if !sharingWasEnabled {
+			cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+				Expect(cf.Cf("disable-feature-flag", "service_instance_sharing").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			})
+		}
+	})
+
+	It("gives apps in the space it is shared into its credentials, binding them in their own space", func() {
+		shareInstance()
+		Expect(getSharedSpaceGuids()).To(ConsistOf(otherSpaceGuid))
+
+		bindAppInOtherSpace()
+		Expect(getAppServices(appGuid)).To(ContainSubstring(password))
+
+		var bindRequests []BrokerRequest
+		for _, request := range broker.GetRequests() {
+			if request.Method == "PUT" && strings.HasPrefix(request.Path, fmt.Sprintf("/v2/service_instances/%s/service_bindings/", instanceGuid)) {
+				bindRequests = append(bindRequests, request)
+			}
+		}
+		Expect(bindRequests).To(HaveLen(1))
+
+		body := bindRequests[0].JSONBody()
+		Expect(body).To(HaveKeyWithValue("app_guid", appGuid))
+		Expect(body).To(HaveKey("context"))
+		Expect(body["context"]).To(HaveKeyWithValue("platform", "cloudfoundry"))
+		Expect(body["context"]).To(HaveKeyWithValue("organization_guid", getGuid("org", context.RegularUserContext().Org)))
+		Expect(body["context"]).To(HaveKeyWithValue("space_guid", otherSpaceGuid))
+	})
+
+	It("unbinds apps in the space it is shared into when it is unshared, staying shared if the broker fails to", func() {
+		shareInstance()
+		bindAppInOtherSpace()
+		bindingPath := fmt.Sprintf("/v2/service_instances/%s/service_bindings/%s", instanceGuid, getBindingGuid(appGuid))
+
+		broker.OnUnbind(broker.SyncPlans[0].ID).RespondWith(500, map[string]string{})
+		Expect(unshareInstance()).To(ContainSubstring(`"errors"`))
+		Expect(broker.GetRequestsTo("DELETE", bindingPath)).NotTo(BeEmpty())
+		Expect(getSharedSpaceGuids()).To(ConsistOf(otherSpaceGuid))
+		Expect(getAppServices(appGuid)).To(ContainSubstring(password))
+
+		By("unsharing once the broker unbinds the app")
+		broker.OnUnbind(broker.SyncPlans[0].ID).RespondWith(200, map[string]string{})
+		Expect(unshareInstance()).NotTo(ContainSubstring(`"errors"`))
+		Expect(getSharedSpaceGuids()).To(BeEmpty())
+
+		// The app is unbound as part of unsharing, without being restaged.
+		bindings := cf.Cf("curl", fmt.Sprintf("/v2/apps/%s/service_bindings", appGuid)).Wait(DEFAULT_TIMEOUT)
+		Expect(bindings).To(Exit(0))
+		Expect(bindings.Out.Contents()).To(ContainSubstring(`"total_results": 0`))
+		Expect(getAppServices(appGuid)).NotTo(ContainSubstring(password))
+	})
+})
@@ -0,0 +1,132 @@
+package services_test
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/services"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Service instance and binding parameters", func() {
+	var (
+		broker       ServiceBroker
+		instanceName string
+		instanceGuid string
+		appName      string
+		appGuid      string
+		bindingGuid  string
+	)
+
+	// getParameters returns the raw response to a /parameters request, which
+	// is either the parameters or a Cloud Controller error.
+	getParameters := func(path string) []byte {
+		session := cf.Cf("curl", path+"/parameters").Wait(DEFAULT_TIMEOUT)
+		Expect(session).To(Exit(0))
+		return session.Out.Contents()
+	}
+
+	instancePath := func() string {
+		return fmt.Sprintf("/v2/service_instances/%s", instanceGuid)
+	}
+
+	bindingPath := func() string {
+		return fmt.Sprintf("/v2/service_bindings/%s", bindingGuid)
+	}
+
+	brokerBindingPath := func() string {
+		return fmt.Sprintf("/v2/service_instances/%s/service_bindings/%s", instanceGuid, bindingGuid)
+	}
+
+	BeforeEach(func() {
+		broker = NewServiceBroker(
+			generator.PrefixedRandomName("prms-brkr-"),
+			assets.NewAssets().GoServiceBroker,
+			context,
+		)
+		broker.Push()
+		broker.Configure()
+		broker.Create()
+		broker.PublicizePlans()
+
+		instanceName = generator.PrefixedRandomName("prms-")
+		createService := cf.Cf("create-service", broker.Service.Name, broker.SyncPlans[0].Name, instanceName, "-c", `{"size":"small"}`).Wait(DEFAULT_TIMEOUT)
+		Expect(createService).To(Exit(0))
+		instanceGuid = getGuid("service", instanceName)
+
+		appName = generator.PrefixedRandomName("CATS-APP-prms-")
+		createApp := cf.Cf("push", appName, "--no-start", "-b", config.RubyBuildpackName, "-m", DEFAULT_MEMORY_LIMIT, "-p", assets.NewAssets().Dora, "-d", config.AppsDomain).Wait(DEFAULT_TIMEOUT)
+		Expect(createApp).To(Exit(0), "failed creating app")
+		appGuid = getGuid("app", appName)
+
+		bindService := cf.Cf("bind-service", appName, instanceName, "-c", `{"role":"reader"}`).Wait(DEFAULT_TIMEOUT)
+		Expect(bindService).To(Exit(0))
+		bindingGuid = getBindingGuid(appGuid)
+	})
+
+	AfterEach(func() {
+		app_helpers.AppReport(broker.Name, DEFAULT_TIMEOUT)
+
+		Expect(cf.Cf("delete", appName, "-f", "-r").Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+		broker.Destroy()
+	})
+
+	Context("when the service allows instances and bindings to be retrieved", func() {
+		It("returns the parameters the instance was created with, and then updated with", func() {
+			Expect(getParameters(instancePath())).To(MatchJSON(`{"size":"small"}`))
+			Expect(broker.GetRequestsTo("GET", instancePath())).To(HaveLen(1))
+
+			updateService := cf.Cf("update-service", instanceName, "-c", `{"size":"large"}`).Wait(DEFAULT_TIMEOUT)
+			Expect(updateService).To(Exit(0))
+
+			Expect(getParameters(instancePath())).To(MatchJSON(`{"size":"large"}`))
+		})
+
+		It("returns the parameters the broker reports, rather than those it was sent", func() {
+			broker.OnGetInstance(broker.SyncPlans[0].ID).RespondWith(200, map[string]interface{}{
+				"parameters": map[string]string{"size": "small", "region": "default-region"},
+			})
+
+			Expect(getParameters(instancePath())).To(MatchJSON(`{"size":"small","region":"default-region"}`))
+		})
+
+		It("returns the parameters the binding was created with", func() {
+			Expect(getParameters(bindingPath())).To(MatchJSON(`{"role":"reader"}`))
+			Expect(broker.GetRequestsTo("GET", brokerBindingPath())).To(HaveLen(1))
+		})
+	})
+
+	Context("when the service does not allow instances or bindings to be retrieved", func() {
+		BeforeEach(func() {
+			catalog := broker.GetCatalog()
+			service := catalog["services"].([]interface{})[0].(map[string]interface{})
+			service["instances_retrievable"] = false
+			service["bindings_retrievable"] = false
+			broker.ServeCatalog(catalog)
+			broker.Update()
+		})
+
+		expectNotSupported := func(path, errorCode string) {
+			var ccError CloudControllerError
+			Expect(json.Unmarshal(getParameters(path), &ccError)).To(Succeed())
+			Expect(ccError.ErrorCode).To(Equal(errorCode))
+		}
+
+		It("fails to return the instance's parameters, without asking the broker", func() {
+			expectNotSupported(instancePath(), "CF-ServiceFetchInstanceParametersNotSupported")
+			Expect(broker.GetRequestsTo("GET", instancePath())).To(BeEmpty())
+		})
+
+		It("fails to return the binding's parameters, without asking the broker", func() {
+			expectNotSupported(bindingPath(), "CF-ServiceFetchBindingParametersNotSupported")
+			Expect(broker.GetRequestsTo("GET", brokerBindingPath())).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,179 @@
+package services_test
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/context_helpers"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/services"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Service plan visibility per organization", func() {
+	var (
+		broker       ServiceBroker
+		brokerGuid   string
+		otherContext *context_helpers.SecondaryContext
+		orgName      string
+		otherOrgName string
+	)
+
+	enableServiceAccess := func(args ...string) {
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			enableAccess := cf.Cf(append([]string{"enable-service-access", broker.Service.Name}, args...)...).Wait(DEFAULT_TIMEOUT)
+			Expect(enableAccess).To(Exit(0))
+		})
+	}
+
+	// marketplace returns what the user sees in the marketplace of the space
+	// they target.
+	marketplace := func() string {
+		marketplace := cf.Cf("marketplace").Wait(DEFAULT_TIMEOUT)
+		Expect(marketplace).To(Exit(0))
+		return string(marketplace.Out.Contents())
+	}
+
+	// getVisiblePlanNames returns the names of the broker's plans that the
+	// user sees through the v2 API.
+	getVisiblePlanNames := func() []string {
+		session := cf.Cf("curl", fmt.Sprintf("/v2/service_plans?q=service_broker_guid:%s", brokerGuid)).Wait(DEFAULT_TIMEOUT)
+		Expect(session).To(Exit(0))
+
+		var plans struct {
+			Resources []ServicePlanResponse `json:"resources"`
+		}
+		Expect(json.Unmarshal(session.Out.Contents(), &plans)).To(Succeed())
+
+		names := []string{}
+		for _, plan := range plans.Resources {
+			names = append(names, plan.Entity.Name)
+		}
+		return names
+	}
+
+	// expectVisiblePlans asserts that the user sees exactly the given plans
+	// of the broker, both in the marketplace and through the v2 API.
+	expectVisiblePlans := func(userContext cf.UserContext, visiblePlans ...Plan) {
+		visible := map[string]bool{}
+		for _, plan := range visiblePlans {
+			visible[plan.Name] = true
+		}
+
+		cf.AsUser(userContext, DEFAULT_TIMEOUT, func() {
+			marketplace := marketplace()
+			if len(visiblePlans) == 0 {
+				Expect(marketplace).NotTo(ContainSubstring(broker.Service.Name))
+			} else {
+				Expect(marketplace).To(ContainSubstring(broker.Service.Name))
+			}
+
+			visiblePlanNames := []string{}
+			for _, plan := range broker.Plans() {
+				if visible[plan.Name] {
+					Expect(marketplace).To(ContainSubstring(plan.Name))
+					visiblePlanNames = append(visiblePlanNames, plan.Name)
+				} else {
+					Expect(marketplace).NotTo(ContainSubstring(plan.Name))
+				}
+			}
+			Expect(getVisiblePlanNames()).To(ConsistOf(visiblePlanNames))
+		})
+	}
+
+	BeforeEach(func() {
+		if config.UseExistingUser {
+			Skip("Per-organization visibility needs a different regular user in each organization")
+		}
+
+		orgName = context.RegularUserContext().Org
+
+		otherContext = context_helpers.NewSecondaryContext(config)
+		otherContext.Setup()
+		otherOrgName = otherContext.RegularUserContext().Org
+
+		broker = NewServiceBroker(
+			generator.PrefixedRandomName("vis-brkr-"),
+			assets.NewAssets().GoServiceBroker,
+			context,
+		)
+		broker.Push()
+		broker.Configure()
+		broker.Create()
+
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			session := cf.Cf("curl", fmt.Sprintf("/v2/service_brokers?q=name:%s", broker.Name)).Wait(DEFAULT_TIMEOUT)
+			Expect(session).To(Exit(0))
+
+			var brokers ServiceInstanceResponse
+			Expect(json.Unmarshal(session.Out.Contents(), &brokers)).To(Succeed())
+			Expect(brokers.Resources).To(HaveLen(1))
+			brokerGuid = brokers.Resources[0].Metadata.Guid
+		})
+	})
+
+	AfterEach(func() {
+		if config.UseExistingUser {
+			return
+		}
+
+		app_helpers.AppReport(broker.Name, DEFAULT_TIMEOUT)
+
+		broker.Destroy()
+		otherContext.Teardown()
+	})
+
+	It("shows no plans to either organization until access is enabled", func() {
+		expectVisiblePlans(context.RegularUserContext())
+		expectVisiblePlans(otherContext.RegularUserContext())
+	})
+
+	It("shows every plan of the service only to the organization it is enabled for", func() {
+		enableServiceAccess("-o", orgName)
+
+		expectVisiblePlans(context.RegularUserContext(), broker.Plans()...)
+		expectVisiblePlans(otherContext.RegularUserContext())
+	})
+
+	It("shows each plan only to the organization it is enabled for", func() {
+		enableServiceAccess("-p", broker.SyncPlans[0].Name, "-o", orgName)
+		enableServiceAccess("-p", broker.SyncPlans[1].Name, "-o", otherOrgName)
+
+		expectVisiblePlans(context.RegularUserContext(), broker.SyncPlans[0])
+		expectVisiblePlans(otherContext.RegularUserContext(), broker.SyncPlans[1])
+	})
+
+	It("shows a plan enabled for every organization alongside plans enabled for one", func() {
+		enableServiceAccess("-p", broker.SyncPlans[0].Name)
+		enableServiceAccess("-p", broker.SyncPlans[1].Name, "-o", otherOrgName)
+
+		expectVisiblePlans(context.RegularUserContext(), broker.SyncPlans[0])
+		expectVisiblePlans(otherContext.RegularUserContext(), broker.SyncPlans[0], broker.SyncPlans[1])
+	})
+
+	It("fails to create an instance of a plan that is not enabled for the organization", func() {
+		enableServiceAccess("-p", broker.SyncPlans[0].Name, "-o", orgName)
+		enableServiceAccess("-p", broker.SyncPlans[1].Name, "-o", otherOrgName)
+
+		instanceName := generator.PrefixedRandomName("vis-")
+		createService := cf.Cf("create-service", broker.Service.Name, broker.SyncPlans[1].Name, instanceName).Wait(DEFAULT_TIMEOUT)
+		Expect(createService).To(Exit(1))
+		Expect(createService).To(Say(fmt.Sprintf("Could not find plan with name %s", broker.SyncPlans[1].Name)))
+
+		Expect(cf.Cf("service", instanceName).Wait(DEFAULT_TIMEOUT)).To(Say("not found"))
+		for _, request := range broker.GetRequests() {
+			Expect(request.Method).NotTo(Equal("PUT"), "the broker was asked to provision %s", request.Path)
+		}
+
+		By("creating an instance of the plan that is enabled")
+		createService = cf.Cf("create-service", broker.Service.Name, broker.SyncPlans[0].Name, instanceName).Wait(DEFAULT_TIMEOUT)
+		Expect(createService).To(Exit(0))
+	})
+})
@@ -1,25 +1,51 @@
 package services_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
 
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
 	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
 	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/services"
 )
 
 var (
-	context helpers.SuiteContext
-	config  helpers.Config
+	context        helpers.SuiteContext
+	config         helpers.Config
+	servicesConfig servicesSuiteConfig
 )
 
+type servicesSuiteConfig struct {
+	helpers.Config
+
+	BrokerClientTimeoutSeconds int `json:"broker_client_timeout_seconds"`
+}
+
+func loadServicesConfig() servicesSuiteConfig {
+	var suiteConfig servicesSuiteConfig
+	err := helpers.Load(helpers.ConfigPath(), &suiteConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	if suiteConfig.BrokerClientTimeoutSeconds == 0 {
+		suiteConfig.BrokerClientTimeoutSeconds = 60
+	}
+	return suiteConfig
+}
+
 func TestApplications(t *testing.T) {
 	RegisterFailHandler(Fail)
 
 	config = helpers.LoadConfig()
+	servicesConfig = loadServicesConfig()
 
 	if config.DefaultTimeout > 0 {
 		DEFAULT_TIMEOUT = config.DefaultTimeout * time.Second
@@ -55,3 +81,35 @@ func TestApplications(t *testing.T) {
 
 	RunSpecsWithDefaultAndCustomReporters(t, componentName, rs)
 }
+
+// getGuid runs a cf command that accepts --guid, e.g. `cf space <name>`.
+func getGuid(args ...string) string {
+	session := cf.Cf(append(args, "--guid")...).Wait(DEFAULT_TIMEOUT)
+	Expect(session).To(Exit(0))
+	return strings.TrimSpace(string(session.Out.Contents()))
+}
+
+// getBindingGuid returns the guid of the app's only service binding.
+func getBindingGuid(appGuid string) string {
+	session := cf.Cf("curl", fmt.Sprintf("/v2/apps/%s/service_bindings", appGuid)).Wait(DEFAULT_TIMEOUT)
+	Expect(session).To(Exit(0))
+
+	var bindings ServiceInstanceResponse
+	Expect(json.Unmarshal(session.Out.Contents(), &bindings)).To(Succeed())
+	Expect(bindings.Resources).To(HaveLen(1))
+	return bindings.Resources[0].Metadata.Guid
+}
+
+// getAppServices returns the app's VCAP_SERVICES.
+func getAppServices(appGuid string) string {
+	session := cf.Cf("curl", fmt.Sprintf("/v2/apps/%s/env", appGuid)).Wait(DEFAULT_TIMEOUT)
+	Expect(session).To(Exit(0))
+
+	var env struct {
+		SystemEnvJson struct {
+			VcapServices json.RawMessage `json:"VCAP_SERVICES"`
+		} `json:"system_env_json"`
+	}
+	Expect(json.Unmarshal(session.Out.Contents(), &env)).To(Succeed())
+	return string(env.SystemEnvJson.VcapServices)
+}
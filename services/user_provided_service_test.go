@@ -0,0 +1,98 @@
+package services_test
+
+import (
+	"encoding/json"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/services"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+type UserProvidedService struct {
+	Name           string            `json:"name"`
+	Label          string            `json:"label"`
+	Credentials    map[string]string `json:"credentials"`
+	SyslogDrainUrl string            `json:"syslog_drain_url"`
+}
+
+var _ = Describe("User-provided service instances", func() {
+	var appName, instanceName string
+
+	// Dora serves its environment, so VCAP_SERVICES is read from the
+	// running app.
+	getUserProvidedServices := func() []UserProvidedService {
+		var vcapServices map[string][]UserProvidedService
+		Expect(json.Unmarshal([]byte(helpers.CurlApp(appName, "/env/VCAP_SERVICES")), &vcapServices)).To(Succeed())
+		return vcapServices["user-provided"]
+	}
+
+	getOnlyUserProvidedService := func() UserProvidedService {
+		services := getUserProvidedServices()
+		Expect(services).To(HaveLen(1))
+		Expect(services[0].Name).To(Equal(instanceName))
+		Expect(services[0].Label).To(Equal("user-provided"))
+		return services[0]
+	}
+
+	restage := func() {
+		Expect(cf.Cf("restage", appName).Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+	}
+
+	BeforeEach(func() {
+		appName = generator.PrefixedRandomName("CATS-APP-ups-")
+		instanceName = generator.PrefixedRandomName("ups-")
+
+		createApp := cf.Cf("push", appName, "--no-start", "-b", config.RubyBuildpackName, "-m", DEFAULT_MEMORY_LIMIT, "-p", assets.NewAssets().Dora, "-d", config.AppsDomain).Wait(DEFAULT_TIMEOUT)
+		Expect(createApp).To(Exit(0), "failed creating app")
+		app_helpers.SetBackend(appName)
+	})
+
+	AfterEach(func() {
+		app_helpers.AppReport(appName, DEFAULT_TIMEOUT)
+
+		Expect(cf.Cf("delete", appName, "-f", "-r").Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("delete-service", instanceName, "-f").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+	})
+
+	It("gives bound apps its credentials, and its updated credentials once they are restaged", func() {
+		Expect(cf.Cf("create-user-provided-service", instanceName, "-p", `{"username":"first-user","password":"first-password"}`).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("bind-service", appName, instanceName).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("start", appName).Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+
+		Expect(getOnlyUserProvidedService().Credentials).To(Equal(map[string]string{
+			"username": "first-user",
+			"password": "first-password",
+		}))
+
+		Expect(cf.Cf("update-user-provided-service", instanceName, "-p", `{"username":"second-user","password":"second-password"}`).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		restage()
+
+		Expect(getOnlyUserProvidedService().Credentials).To(Equal(map[string]string{
+			"username": "second-user",
+			"password": "second-password",
+		}))
+	})
+
+	It("gives bound apps its syslog drain URL, and its updated syslog drain URL once they are restaged", func() {
+		firstDrainUrl := "syslog://first-drain.example.com:514"
+		secondDrainUrl := "syslog://second-drain.example.com:514"
+
+		Expect(cf.Cf("create-user-provided-service", instanceName, "-l", firstDrainUrl).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("bind-service", appName, instanceName).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("start", appName).Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+
+		Expect(getOnlyUserProvidedService().SyslogDrainUrl).To(Equal(firstDrainUrl))
+
+		Expect(cf.Cf("update-user-provided-service", instanceName, "-l", secondDrainUrl).Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		restage()
+
+		Expect(getOnlyUserProvidedService().SyslogDrainUrl).To(Equal(secondDrainUrl))
+	})
+})
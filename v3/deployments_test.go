@@ -0,0 +1,259 @@
+package v3
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/runner"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/v3_helpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("v3 rolling deployments", func() {
+	var (
+		appName           string
+		appGuid           string
+		packageGuid       string
+		token             string
+		originalDroplet   string
+		newDroplet        string
+		webProcess        Process
+		processCount      int
+		originalInstances map[string]bool
+		poller            *instancePoller
+		instances         = 3
+	)
+
+	BeforeEach(func() {
+		poller = nil
+		appName = generator.PrefixedRandomName("CATS-APP-")
+		spaceGuid := GetSpaceGuidFromName(context.RegularUserContext().Space)
+		appGuid = CreateApp(appName, spaceGuid, `{"foo":"bar"}`)
+		packageGuid = CreatePackage(appGuid)
+		token = GetAuthToken()
+		uploadUrl := fmt.Sprintf("%s%s/v3/packages/%s/upload", config.Protocol(), config.ApiEndpoint, packageGuid)
+
+		UploadPackage(uploadUrl, assets.NewAssets().DoraZip, token)
+		WaitForPackageToBeReady(packageGuid)
+
+		originalDroplet = StageBuildpackPackage(packageGuid, config.RubyBuildpackName)
+		WaitForDropletToStage(originalDroplet)
+		AssignDropletToApp(appGuid, originalDroplet)
+
+		processes := GetProcesses(appGuid, appName)
+		processCount = len(processes)
+		webProcess = GetProcessByType(processes, "web")
+		Expect(webProcess.Guid).ToNot(BeEmpty())
+
+		CreateAndMapRoute(appGuid, context.RegularUserContext().Space, config.AppsDomain, webProcess.Name)
+
+		ScaleProcess(appGuid, "web", strconv.Itoa(instances), "", "")
+
+		StartApp(appGuid)
+
+		By("waiting for every original instance to serve requests")
+		originalInstances = map[string]bool{}
+		Eventually(func() int {
+			if instanceId, ok := curlInstanceId(webProcess.Name); ok {
+				originalInstances[instanceId] = true
+			}
+			return len(originalInstances)
+		}, CF_PUSH_TIMEOUT).Should(Equal(instances))
+
+		By("staging a new droplet for the running app")
+		newDroplet = StageBuildpackPackage(packageGuid, config.RubyBuildpackName)
+		WaitForDropletToStage(newDroplet)
+
+		poller = startPollingInstances(webProcess.Name)
+	})
+
+	AfterEach(func() {
+		if poller != nil {
+			poller.Stop()
+		}
+
+		FetchRecentLogs(appGuid, token, config)
+		DeleteApp(appGuid)
+	})
+
+	It("gradually replaces the instances without failing requests", func() {
+		deploymentGuid := CreateDeployment(appGuid, newDroplet)
+		deployment := GetDeployment(deploymentGuid)
+		Expect(deployment.State).To(Equal("DEPLOYING"))
+		Expect(deployment.Droplet.Guid).To(Equal(newDroplet))
+
+		Eventually(func() string {
+			return GetDeployment(deploymentGuid).State
+		}, CF_PUSH_TIMEOUT).Should(Equal("DEPLOYED"))
+
+		Eventually(func() []string {
+			return newInstances(poller.Responses(), originalInstances)
+		}, DEFAULT_TIMEOUT).Should(HaveLen(instances))
+
+		Eventually(func() []string {
+			return originalInstancesIn(poller.LastResponses(instances*3), originalInstances)
+		}, DEFAULT_TIMEOUT).Should(BeEmpty())
+
+		poller.Stop()
+		Expect(poller.Failures()).To(BeZero())
+
+		By("serving from old and new instances at the same time during the rollout")
+		responses := poller.Responses()
+		firstNew, lastOriginal := -1, -1
+		for i, instanceId := range responses {
+			if originalInstances[instanceId] {
+				lastOriginal = i
+			} else if firstNew == -1 {
+				firstNew = i
+			}
+		}
+		Expect(firstNew).NotTo(Equal(-1))
+		Expect(firstNew).To(BeNumerically("<", lastOriginal))
+	})
+
+	It("rolls back to the original droplet when the deployment is cancelled mid-rollout", func() {
+		deploymentGuid := CreateDeployment(appGuid, newDroplet)
+
+		By("waiting for the first new instance to serve requests")
+		Eventually(func() []string {
+			return newInstances(poller.Responses(), originalInstances)
+		}, CF_PUSH_TIMEOUT).ShouldNot(BeEmpty())
+		Expect(GetDeployment(deploymentGuid).State).To(Equal("DEPLOYING"))
+
+		CancelDeployment(deploymentGuid)
+
+		Eventually(func() string {
+			return GetDeployment(deploymentGuid).State
+		}, CF_PUSH_TIMEOUT).Should(Equal("CANCELED"))
+
+		session := cf.Cf("curl", fmt.Sprintf("/v3/apps/%s/droplets/current", appGuid))
+		Expect(session.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		var currentDroplet struct {
+			Guid string `json:"guid"`
+		}
+		Expect(json.Unmarshal(session.Out.Contents(), &currentDroplet)).To(Succeed())
+		Expect(currentDroplet.Guid).To(Equal(originalDroplet))
+
+		Eventually(func() []Process {
+			return GetProcesses(appGuid, appName)
+		}, DEFAULT_TIMEOUT).Should(HaveLen(processCount))
+		Expect(GetProcessByType(GetProcesses(appGuid, appName), "web").Guid).To(Equal(webProcess.Guid))
+
+		poller.Stop()
+		Expect(poller.Failures()).To(BeZero())
+	})
+})
+
+// instancePoller continuously curls an app's /id endpoint, recording which
+// instance answered each request and how many requests failed.
+type instancePoller struct {
+	mutex     sync.Mutex
+	responses []string
+	failures  int
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func startPollingInstances(hostname string) *instancePoller {
+	poller := &instancePoller{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer GinkgoRecover()
+		defer close(poller.done)
+
+		for {
+			select {
+			case <-poller.stop:
+				return
+			default:
+			}
+
+			instanceId, ok := curlInstanceId(hostname)
+
+			poller.mutex.Lock()
+			if ok {
+				poller.responses = append(poller.responses, instanceId)
+			} else {
+				poller.failures++
+			}
+			poller.mutex.Unlock()
+		}
+	}()
+
+	return poller
+}
+
+func (poller *instancePoller) Stop() {
+	poller.stopOnce.Do(func() {
+		close(poller.stop)
+	})
+	<-poller.done
+}
+
+func (poller *instancePoller) Responses() []string {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+	return append([]string{}, poller.responses...)
+}
+
+func (poller *instancePoller) LastResponses(count int) []string {
+	responses := poller.Responses()
+	if len(responses) > count {
+		responses = responses[len(responses)-count:]
+	}
+	return responses
+}
+
+func (poller *instancePoller) Failures() int {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+	return poller.failures
+}
+
+// curlInstanceId returns the id of the instance that answered, or false if
+// the request did not succeed.
+func curlInstanceId(hostname string) (string, bool) {
+	curl := runner.Curl(helpers.AppUri(hostname, "/id"), "-w", "\n%{http_code}").Wait(DEFAULT_TIMEOUT)
+	lines := strings.Split(strings.TrimSpace(string(curl.Out.Contents())), "\n")
+	if curl.ExitCode() != 0 || len(lines) != 2 || lines[1] != "200" {
+		return "", false
+	}
+	return lines[0], true
+}
+
+func newInstances(responses []string, originalInstances map[string]bool) []string {
+	seen := map[string]bool{}
+	var instanceIds []string
+	for _, instanceId := range responses {
+		if !originalInstances[instanceId] && !seen[instanceId] {
+			seen[instanceId] = true
+			instanceIds = append(instanceIds, instanceId)
+		}
+	}
+	return instanceIds
+}
+
+func originalInstancesIn(responses []string, originalInstances map[string]bool) []string {
+	var instanceIds []string
+	for _, instanceId := range responses {
+		if originalInstances[instanceId] {
+			instanceIds = append(instanceIds, instanceId)
+		}
+	}
+	return instanceIds
+}
@@ -0,0 +1,174 @@
+package v3
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/v3_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+	archive_helpers "github.com/pivotal-golang/archiver/extractor/test_helper"
+)
+
+var _ = Describe("multiple buildpacks", func() {
+	var (
+		appName             string
+		appGuid             string
+		packageGuid         string
+		token               string
+		supplyBuildpacks    []string
+		finalBuildpack      string
+		buildpackArchiveDir string
+	)
+
+	BeforeEach(func() {
+		appName = generator.PrefixedRandomName("CATS-APP-")
+		spaceGuid := GetSpaceGuidFromName(context.RegularUserContext().Space)
+		appGuid = CreateApp(appName, spaceGuid, "{}")
+		packageGuid = CreatePackage(appGuid)
+		token = GetAuthToken()
+		uploadUrl := fmt.Sprintf("%s%s/v3/packages/%s/upload", config.Protocol(), config.ApiEndpoint, packageGuid)
+		UploadPackage(uploadUrl, assets.NewAssets().DoraZip, token)
+		WaitForPackageToBeReady(packageGuid)
+
+		var err error
+		buildpackArchiveDir, err = ioutil.TempDir("", "multi-buildpack-cats")
+		Expect(err).ToNot(HaveOccurred())
+
+		supplyBuildpacks = []string{
+			generator.PrefixedRandomName("CATS-SUPPLY-BP-"),
+			generator.PrefixedRandomName("CATS-SUPPLY-BP-"),
+		}
+		finalBuildpack = generator.PrefixedRandomName("CATS-FINAL-BP-")
+
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			for _, buildpackName := range supplyBuildpacks {
+				buildpackZip := createSupplyBuildpack(buildpackArchiveDir, buildpackName)
+				Expect(cf.Cf("create-buildpack", buildpackName, buildpackZip, "999").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			}
+			buildpackZip := createFinalBuildpack(buildpackArchiveDir, finalBuildpack)
+			Expect(cf.Cf("create-buildpack", finalBuildpack, buildpackZip, "999").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		})
+	})
+
+	AfterEach(func() {
+		FetchRecentLogs(appGuid, token, config)
+
+		cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+			for _, buildpackName := range append(supplyBuildpacks, finalBuildpack) {
+				Expect(cf.Cf("delete-buildpack", buildpackName, "-f").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+			}
+		})
+		DeleteApp(appGuid)
+		Expect(os.RemoveAll(buildpackArchiveDir)).To(Succeed())
+	})
+
+	It("runs every supply buildpack in order and starts the app with the last buildpack's release", func() {
+		dropletGuid := StageWithBuildpacks(packageGuid, append(supplyBuildpacks, finalBuildpack)...)
+		WaitForDropletToStage(dropletGuid)
+
+		droplet := GetDroplet(dropletGuid)
+		Expect(droplet.ProcessTypes).To(HaveKeyWithValue("web", finalBuildpackWebCommand))
+
+		var buildpackNames []string
+		for _, buildpack := range droplet.Buildpacks {
+			buildpackNames = append(buildpackNames, buildpack.Name)
+		}
+		Expect(buildpackNames).To(Equal([]string{supplyBuildpacks[0], supplyBuildpacks[1], finalBuildpack}))
+
+		AssignDropletToApp(appGuid, dropletGuid)
+		webProcess := GetProcessByType(GetProcesses(appGuid, appName), "web")
+		Expect(webProcess.Command).To(Equal(finalBuildpackWebCommand))
+
+		CreateAndMapRoute(appGuid, context.RegularUserContext().Space, config.AppsDomain, webProcess.Name)
+		StartApp(appGuid)
+
+		expectedOrder := fmt.Sprintf("supplied by %s at index 0\nsupplied by %s at index 1\nfinalized by %s at index 2",
+			supplyBuildpacks[0], supplyBuildpacks[1], finalBuildpack)
+		Eventually(func() string {
+			return helpers.CurlAppRoot(webProcess.Name)
+		}, CF_PUSH_TIMEOUT).Should(ContainSubstring(expectedOrder))
+	})
+})
+
+// The web command serves the record each buildpack appended to while
+// staging, in the order they ran.
+const finalBuildpackWebCommand = `while true; do { echo -e 'HTTP/1.1 200 OK\r\n'; cat buildpack-order; } | nc -l $PORT; done`
+
+// createSupplyBuildpack creates a buildpack that can only supply
+// dependencies. Its release would start the app with the wrong command, so
+// that the app only serves requests if the final buildpack's release is used.
+func createSupplyBuildpack(archiveDir, buildpackName string) string {
+	buildpackArchivePath := path.Join(archiveDir, buildpackName+".zip")
+
+	archive_helpers.CreateZipArchive(buildpackArchivePath, []archive_helpers.ArchiveFile{
+		{
+			Name: "bin/supply",
+			Body: fmt.Sprintf(`#!/usr/bin/env bash
+
+echo "supplied by %s at index $4" >> $1/buildpack-order
+`, buildpackName),
+		},
+		{
+			Name: "bin/detect",
+			Body: `#!/bin/bash
+echo no
+exit 1
+`,
+		},
+		{
+			Name: "bin/release",
+			Body: `#!/usr/bin/env bash
+
+cat <<EOF
+---
+default_process_types:
+  web: echo "released by a supply buildpack" && exit 1
+EOF
+`,
+		},
+	})
+
+	return buildpackArchivePath
+}
+
+func createFinalBuildpack(archiveDir, buildpackName string) string {
+	buildpackArchivePath := path.Join(archiveDir, buildpackName+".zip")
+
+	archive_helpers.CreateZipArchive(buildpackArchivePath, []archive_helpers.ArchiveFile{
+		{
+			Name: "bin/finalize",
+			Body: fmt.Sprintf(`#!/usr/bin/env bash
+
+echo -n "finalized by %s at index $4" >> $1/buildpack-order
+`, buildpackName),
+		},
+		{
+			Name: "bin/detect",
+			Body: `#!/bin/bash
+echo no
+exit 1
+`,
+		},
+		{
+			Name: "bin/release",
+			Body: fmt.Sprintf(`#!/usr/bin/env bash
+
+cat <<'EOF'
+---
+default_process_types:
+  web: %s
+EOF
+`, finalBuildpackWebCommand),
+		},
+	})
+
+	return buildpackArchivePath
+}
@@ -1,7 +1,6 @@
 package v3
 
 import (
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -9,18 +8,13 @@ import (
 	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
 	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
 	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/matchers"
 	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/v3_helpers"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
 )
 
-type ProcessStats struct {
-	Instance []struct {
-		State string `json:"state"`
-	} `json:"resources"`
-}
-
 var _ = Describe("process", func() {
 	var (
 		appName     string
@@ -75,13 +69,8 @@ var _ = Describe("process", func() {
 
 		Context("/v3/apps/:guid/processes/:type/instances/:index", func() {
 			It("restarts the instance", func() {
-				statsUrl := fmt.Sprintf("/v3/apps/%s/processes/web/stats", appGuid)
-
 				By("ensuring the instance is running")
-				statsBody := cf.Cf("curl", statsUrl).Wait(DEFAULT_TIMEOUT).Out.Contents()
-				statsJSON := ProcessStats{}
-				json.Unmarshal(statsBody, &statsJSON)
-				Expect(statsJSON.Instance[0].State).To(Equal("RUNNING"))
+				Expect(GetProcessStatsByType(appGuid, "web")).To(HaveInstancesInState(1, "RUNNING"))
 
 				By("terminating the instance")
 				terminateUrl := fmt.Sprintf("/v3/apps/%s/processes/%s/instances/%d", appGuid, processType, index)
@@ -89,30 +78,21 @@ var _ = Describe("process", func() {
 
 				By("ensuring the instance is no longer running")
 				// Note that this depends on a 30s run loop waking up in Diego.
-				Eventually(func() string {
-					statsBodyAfter := cf.Cf("curl", statsUrl).Wait(DEFAULT_TIMEOUT).Out.Contents()
-					json.Unmarshal(statsBodyAfter, &statsJSON)
-					return statsJSON.Instance[0].State
-				}, 35*time.Second).ShouldNot(Equal("RUNNING"))
+				Eventually(func() ProcessStats {
+					return GetProcessStatsByType(appGuid, "web")
+				}, 35*time.Second).ShouldNot(HaveInstancesInState(1, "RUNNING"))
 
 				By("ensuring the instance is running again")
-				Eventually(func() string {
-					statsBodyAfter := cf.Cf("curl", statsUrl).Wait(DEFAULT_TIMEOUT).Out.Contents()
-					json.Unmarshal(statsBodyAfter, &statsJSON)
-					return statsJSON.Instance[0].State
-				}, 35*time.Second).Should(Equal("RUNNING"))
+				Eventually(func() ProcessStats {
+					return GetProcessStatsByType(appGuid, "web")
+				}, 35*time.Second).Should(HaveInstancesInState(1, "RUNNING"))
 			})
 		})
 
 		Context("/v3/processes/:guid/instances/:index", func() {
 			It("restarts the instance", func() {
-				statsUrl := fmt.Sprintf("/v3/apps/%s/processes/web/stats", appGuid)
-
 				By("ensuring the instance is running")
-				statsBody := cf.Cf("curl", statsUrl).Wait(DEFAULT_TIMEOUT).Out.Contents()
-				statsJSON := ProcessStats{}
-				json.Unmarshal(statsBody, &statsJSON)
-				Expect(statsJSON.Instance[0].State).To(Equal("RUNNING"))
+				Expect(GetProcessStats(webProcess.Guid)).To(HaveInstancesInState(1, "RUNNING"))
 
 				By("terminating the instance")
 				terminateUrl := fmt.Sprintf("/v3/processes/%s/instances/%d", webProcess.Guid, index)
@@ -120,19 +100,119 @@ var _ = Describe("process", func() {
 
 				By("ensuring the instance is no longer running")
 				// Note that this depends on a 30s run loop waking up in Diego.
-				Eventually(func() string {
-					statsBodyAfter := cf.Cf("curl", statsUrl).Wait(DEFAULT_TIMEOUT).Out.Contents()
-					json.Unmarshal(statsBodyAfter, &statsJSON)
-					return statsJSON.Instance[0].State
-				}, 35*time.Second).ShouldNot(Equal("RUNNING"))
+				Eventually(func() ProcessStats {
+					return GetProcessStats(webProcess.Guid)
+				}, 35*time.Second).ShouldNot(HaveInstancesInState(1, "RUNNING"))
 
 				By("ensuring the instance is running again")
-				Eventually(func() string {
-					statsBodyAfter := cf.Cf("curl", statsUrl).Wait(DEFAULT_TIMEOUT).Out.Contents()
-					json.Unmarshal(statsBodyAfter, &statsJSON)
-					return statsJSON.Instance[0].State
-				}, 35*time.Second).Should(Equal("RUNNING"))
+				Eventually(func() ProcessStats {
+					return GetProcessStats(webProcess.Guid)
+				}, 35*time.Second).Should(HaveInstancesInState(1, "RUNNING"))
 			})
 		})
 	})
+
+	Describe("scaling processes and configuring their health checks", func() {
+		var (
+			webProcess    Process
+			workerProcess Process
+		)
+
+		BeforeEach(func() {
+			dropletGuid := StageBuildpackPackage(packageGuid, "ruby_buildpack")
+			WaitForDropletToStage(dropletGuid)
+
+			AssignDropletToApp(appGuid, dropletGuid)
+
+			processes := GetProcesses(appGuid, appName)
+			webProcess = GetProcessByType(processes, "web")
+			workerProcess = GetProcessByType(processes, "worker")
+			Expect(webProcess.Guid).ToNot(BeEmpty())
+			Expect(workerProcess.Guid).ToNot(BeEmpty())
+
+			CreateAndMapRoute(appGuid, context.RegularUserContext().Space, helpers.LoadConfig().AppsDomain, webProcess.Name)
+		})
+
+		It("scales the web and worker processes independently", func() {
+			ScaleProcessByGuid(webProcess.Guid, "2", "256", "512")
+			ScaleProcessByGuid(workerProcess.Guid, "1", "128", "256")
+
+			StartApp(appGuid)
+
+			Eventually(func() ProcessStats {
+				return GetProcessStats(webProcess.Guid)
+			}, CF_PUSH_TIMEOUT).Should(HaveInstancesInState(2, "RUNNING"))
+			Eventually(func() ProcessStats {
+				return GetProcessStats(workerProcess.Guid)
+			}, CF_PUSH_TIMEOUT).Should(HaveInstancesInState(1, "RUNNING"))
+
+			web := GetProcessByGuid(webProcess.Guid)
+			Expect(web.Instances).To(Equal(2))
+			Expect(web.MemoryInMb).To(Equal(256))
+			Expect(web.DiskInMb).To(Equal(512))
+
+			worker := GetProcessByGuid(workerProcess.Guid)
+			Expect(worker.Instances).To(Equal(1))
+			Expect(worker.MemoryInMb).To(Equal(128))
+			Expect(worker.DiskInMb).To(Equal(256))
+
+			for _, instance := range GetProcessStats(webProcess.Guid).Instances {
+				Expect(instance.MemQuota).To(Equal(256 * 1024 * 1024))
+				Expect(instance.DiskQuota).To(Equal(512 * 1024 * 1024))
+			}
+
+			By("scaling the worker process without affecting the web process")
+			ScaleProcessByGuid(workerProcess.Guid, "3", "", "")
+
+			Eventually(func() ProcessStats {
+				return GetProcessStats(workerProcess.Guid)
+			}, CF_PUSH_TIMEOUT).Should(HaveInstancesInState(3, "RUNNING"))
+			Expect(GetProcessStats(webProcess.Guid)).To(HaveInstancesInState(2, "RUNNING"))
+			Expect(GetProcessByGuid(webProcess.Guid).Instances).To(Equal(2))
+		})
+
+		It("runs each process with its own health check", func() {
+			UpdateProcessHealthCheck(webProcess.Guid, "http", "/", 60)
+			UpdateProcessHealthCheck(workerProcess.Guid, "process", "", 0)
+
+			web := GetProcessByGuid(webProcess.Guid)
+			Expect(web.HealthCheck.Type).To(Equal("http"))
+			Expect(web.HealthCheck.Data.Endpoint).To(Equal("/"))
+			Expect(web.HealthCheck.Data.Timeout).To(Equal(60))
+			Expect(GetProcessByGuid(workerProcess.Guid).HealthCheck.Type).To(Equal("process"))
+
+			StartApp(appGuid)
+
+			Eventually(func() ProcessStats {
+				return GetProcessStats(webProcess.Guid)
+			}, CF_PUSH_TIMEOUT).Should(HaveInstancesInState(1, "RUNNING"))
+			Eventually(func() ProcessStats {
+				return GetProcessStats(workerProcess.Guid)
+			}, CF_PUSH_TIMEOUT).Should(HaveInstancesInState(1, "RUNNING"))
+
+			By("switching the web process to a port health check")
+			StopApp(appGuid)
+			UpdateProcessHealthCheck(webProcess.Guid, "port", "", 0)
+			Expect(GetProcessByGuid(webProcess.Guid).HealthCheck.Type).To(Equal("port"))
+			StartApp(appGuid)
+
+			Eventually(func() ProcessStats {
+				return GetProcessStats(webProcess.Guid)
+			}, CF_PUSH_TIMEOUT).Should(HaveInstancesInState(1, "RUNNING"))
+		})
+
+		It("does not run instances whose http health check endpoint fails", func() {
+			UpdateProcessHealthCheck(webProcess.Guid, "http", "/not-a-real-endpoint", 10)
+			UpdateProcessHealthCheck(workerProcess.Guid, "process", "", 0)
+
+			StartApp(appGuid)
+
+			Eventually(func() ProcessStats {
+				return GetProcessStats(workerProcess.Guid)
+			}, CF_PUSH_TIMEOUT).Should(HaveInstancesInState(1, "RUNNING"))
+			Eventually(func() ProcessStats {
+				return GetProcessStats(webProcess.Guid)
+			}, CF_PUSH_TIMEOUT).Should(HaveInstancesInState(1, "CRASHED"))
+		})
+	})
 })
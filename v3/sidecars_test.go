@@ -0,0 +1,130 @@
+package v3
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/app_helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/v3_helpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+type sidecarAppInfo struct {
+	Id       string `json:"id"`
+	Hostname string `json:"hostname"`
+	Pid      int    `json:"pid"`
+}
+
+var _ = Describe("v3 sidecars", func() {
+	var (
+		appName        string
+		appGuid        string
+		spaceGuid      string
+		sidecarName    = "cats-sidecar"
+		sidecarCommand = "sidecar-app sidecar"
+	)
+
+	BeforeEach(func() {
+		appName = generator.PrefixedRandomName("CATS-APP-")
+		spaceGuid = GetSpaceGuidFromName(context.RegularUserContext().Space)
+
+		Expect(cf.Cf("push", appName,
+			"--no-start",
+			"-b", config.GoBuildpackName,
+			"-m", DEFAULT_MEMORY_LIMIT+"M",
+			"-p", assets.NewAssets().SidecarApp,
+			"-d", config.AppsDomain,
+		).Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+		appGuid = app_helpers.GetAppGuid(appName)
+	})
+
+	AfterEach(func() {
+		app_helpers.AppReport(appName, DEFAULT_TIMEOUT)
+		Expect(cf.Cf("delete", appName, "-f", "-r").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+	})
+
+	itRunsTheSidecarWithTheWebProcess := func() {
+		It("runs the sidecar in the web process's container and restarts it with the web process", func() {
+			Expect(cf.Cf("start", appName).Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+
+			By("sharing the web process's filesystem")
+			var sidecarInfo sidecarAppInfo
+			Eventually(func() string {
+				sidecarInfo = getSidecarAppInfo(appName, "/sidecar/file")
+				return sidecarInfo.Id
+			}, DEFAULT_TIMEOUT).ShouldNot(BeEmpty())
+
+			webInfo := getSidecarAppInfo(appName, "/info")
+			Expect(sidecarInfo.Hostname).To(Equal(webInfo.Hostname))
+			Expect(sidecarInfo.Pid).NotTo(Equal(webInfo.Pid))
+
+			By("sharing the web process's network namespace")
+			Expect(getSidecarAppInfo(appName, "/sidecar")).To(Equal(sidecarInfo))
+
+			By("restarting the sidecar when the web process is restarted")
+			Expect(cf.Cf("restart", appName).Wait(CF_PUSH_TIMEOUT)).To(Exit(0))
+
+			var restartedSidecarInfo sidecarAppInfo
+			Eventually(func() string {
+				restartedSidecarInfo = getSidecarAppInfo(appName, "/sidecar")
+				return restartedSidecarInfo.Id
+			}, DEFAULT_TIMEOUT).ShouldNot(SatisfyAny(BeEmpty(), Equal(sidecarInfo.Id)))
+
+			restartedWebInfo := getSidecarAppInfo(appName, "/info")
+			Expect(restartedWebInfo.Id).NotTo(Equal(webInfo.Id))
+			Expect(restartedSidecarInfo.Hostname).To(Equal(restartedWebInfo.Hostname))
+		})
+	}
+
+	Context("when the sidecar is created through the API", func() {
+		BeforeEach(func() {
+			sidecarGuid := CreateSidecar(appGuid, sidecarName, sidecarCommand, []string{"web"})
+
+			sidecars := GetSidecars(appGuid)
+			Expect(sidecars).To(HaveLen(1))
+			Expect(sidecars[0].Guid).To(Equal(sidecarGuid))
+			Expect(sidecars[0].Name).To(Equal(sidecarName))
+			Expect(sidecars[0].Command).To(Equal(sidecarCommand))
+			Expect(sidecars[0].ProcessTypes).To(ConsistOf("web"))
+		})
+
+		itRunsTheSidecarWithTheWebProcess()
+	})
+
+	Context("when the sidecar is declared in the app manifest", func() {
+		BeforeEach(func() {
+			ApplyManifest(spaceGuid, fmt.Sprintf(`---
+applications:
+- name: %s
+  sidecars:
+  - name: %s
+    command: %s
+    process_types:
+    - web
+`, appName, sidecarName, sidecarCommand))
+
+			sidecars := GetSidecars(appGuid)
+			Expect(sidecars).To(HaveLen(1))
+			Expect(sidecars[0].Name).To(Equal(sidecarName))
+			Expect(sidecars[0].Command).To(Equal(sidecarCommand))
+			Expect(sidecars[0].ProcessTypes).To(ConsistOf("web"))
+		})
+
+		itRunsTheSidecarWithTheWebProcess()
+	})
+})
+
+// getSidecarAppInfo returns an empty sidecarAppInfo while the endpoint is
+// unavailable, so that it can be polled.
+func getSidecarAppInfo(appName, path string) sidecarAppInfo {
+	var info sidecarAppInfo
+	json.Unmarshal([]byte(helpers.CurlApp(appName, path)), &info)
+	return info
+}
@@ -0,0 +1,196 @@
+package v3
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/cf-test-helpers/cf"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/generator"
+	"github.com/cloudfoundry-incubator/cf-test-helpers/helpers"
+	"github.com/cloudfoundry/cf-acceptance-tests/helpers/assets"
+	. "github.com/cloudfoundry/cf-acceptance-tests/helpers/v3_helpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("v3 task scheduling", func() {
+	var (
+		appName   string
+		appGuid   string
+		spaceGuid string
+		token     string
+	)
+
+	BeforeEach(func() {
+		appName = generator.PrefixedRandomName("CATS-APP-")
+		spaceGuid = GetSpaceGuidFromName(context.RegularUserContext().Space)
+		appGuid = CreateApp(appName, spaceGuid, `{"foo":"bar"}`)
+		packageGuid := CreatePackage(appGuid)
+		token = GetAuthToken()
+		uploadUrl := fmt.Sprintf("%s%s/v3/packages/%s/upload", config.Protocol(), config.ApiEndpoint, packageGuid)
+		UploadPackage(uploadUrl, assets.NewAssets().DoraZip, token)
+		WaitForPackageToBeReady(packageGuid)
+		dropletGuid := StageBuildpackPackage(packageGuid, "ruby_buildpack")
+		WaitForDropletToStage(dropletGuid)
+		AssignDropletToApp(appGuid, dropletGuid)
+	})
+
+	AfterEach(func() {
+		FetchRecentLogs(appGuid, token, config)
+		DeleteApp(appGuid)
+	})
+
+	taskState := func(taskGuid string) func() string {
+		return func() string {
+			return GetTask(taskGuid).State
+		}
+	}
+
+	expectUsageEvent := func(state string, task Task) {
+		event := AppUsageEvent{Entity{State: state, ParentAppGuid: appGuid, ParentAppName: appName, TaskGuid: task.Guid}}
+		Eventually(func() bool {
+			return UsageEventsInclude(LastPageUsageEvents(context), event)
+		}, DEFAULT_TIMEOUT).Should(BeTrue(), "missing %s usage event for task %s", state, task.Guid)
+	}
+
+	config := helpers.LoadConfig()
+
+	if config.IncludeTasks {
+		Context("when running many tasks against a space memory quota", func() {
+			var (
+				quotaGuid    string
+				taskMemory   = 256
+				maxTasks     = 4
+				runningTasks []Task
+			)
+
+			BeforeEach(func() {
+				quotaGuid = createSpaceQuota(spaceGuid, taskMemory*maxTasks)
+				runningTasks = nil
+			})
+
+			AfterEach(func() {
+				for _, task := range runningTasks {
+					CancelTask(task.Guid)
+				}
+				deleteSpaceQuota(quotaGuid, spaceGuid)
+			})
+
+			It("runs tasks concurrently up to the quota and rejects the rest", func() {
+				for i := 0; i < maxTasks; i++ {
+					task, _ := CreateTask(appGuid, "sleep 300", fmt.Sprintf("concurrent-%d", i), taskMemory)
+					Expect(task.Guid).NotTo(BeEmpty())
+					runningTasks = append(runningTasks, task)
+				}
+
+				for _, task := range runningTasks {
+					Eventually(taskState(task.Guid), DEFAULT_TIMEOUT).Should(Equal("RUNNING"))
+					expectUsageEvent("TASK_STARTED", task)
+				}
+
+				By("rejecting a task that would exceed the memory quota")
+				rejectedTask, response := CreateTask(appGuid, "sleep 300", "over-quota", taskMemory)
+				Expect(rejectedTask.Guid).To(BeEmpty())
+				Expect(string(response)).To(ContainSubstring("memory"))
+
+				By("accepting a task again once one has been cancelled")
+				cancelledTask := runningTasks[0]
+				CancelTask(cancelledTask.Guid)
+				runningTasks = runningTasks[1:]
+				Eventually(taskState(cancelledTask.Guid), DEFAULT_TIMEOUT).Should(Equal("FAILED"))
+				expectUsageEvent("TASK_STOPPED", cancelledTask)
+
+				task, _ := CreateTask(appGuid, "sleep 300", "within-quota", taskMemory)
+				Expect(task.Guid).NotTo(BeEmpty())
+				runningTasks = append(runningTasks, task)
+				Eventually(taskState(task.Guid), DEFAULT_TIMEOUT).Should(Equal("RUNNING"))
+			})
+		})
+
+		Context("when a task exceeds its memory limit", func() {
+			It("is killed and fails with an out of memory reason", func() {
+				// tail buffers a line in memory until it sees a newline, which never comes
+				task, _ := CreateTask(appGuid, "head -c 1G /dev/zero | tail", "oom", 64)
+				Expect(task.MemoryInMb).To(Equal(64))
+
+				Eventually(taskState(task.Guid), CF_PUSH_TIMEOUT).Should(Equal("FAILED"))
+				Expect(GetTask(task.Guid).Result.FailureReason).To(MatchRegexp("(?i)out of memory|oom"))
+
+				expectUsageEvent("TASK_STARTED", task)
+				expectUsageEvent("TASK_STOPPED", task)
+			})
+		})
+
+		Context("when a task's command exits non-zero", func() {
+			It("fails with the exit status as the reason", func() {
+				task, _ := CreateTask(appGuid, "exit 42", "exits-non-zero", 0)
+
+				Eventually(taskState(task.Guid), DEFAULT_TIMEOUT).Should(Equal("FAILED"))
+				Expect(GetTask(task.Guid).Result.FailureReason).To(ContainSubstring("status 42"))
+
+				expectUsageEvent("TASK_STARTED", task)
+				expectUsageEvent("TASK_STOPPED", task)
+			})
+		})
+
+		Context("naming and sequencing tasks", func() {
+			It("gives each task of an app the next sequence id, and a name if none is given", func() {
+				firstTask, _ := CreateTask(appGuid, "echo 0", "first", 0)
+				secondTask, _ := CreateTask(appGuid, "echo 0", "", 0)
+
+				Expect(firstTask.Name).To(Equal("first"))
+				Expect(secondTask.Name).NotTo(BeEmpty())
+				Expect(secondTask.SequenceId).To(Equal(firstTask.SequenceId + 1))
+
+				for _, task := range []Task{firstTask, secondTask} {
+					Eventually(taskState(task.Guid), DEFAULT_TIMEOUT).Should(Equal("SUCCEEDED"))
+					Expect(GetTask(task.Guid).SequenceId).To(Equal(task.SequenceId))
+
+					expectUsageEvent("TASK_STARTED", task)
+					expectUsageEvent("TASK_STOPPED", task)
+				}
+			})
+		})
+	}
+})
+
+func createSpaceQuota(spaceGuid string, memoryLimitInMb int) string {
+	var quotaGuid string
+
+	cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+		spaceSession := cf.Cf("curl", fmt.Sprintf("/v2/spaces/%s", spaceGuid))
+		Expect(spaceSession.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		var space struct {
+			Entity struct {
+				OrganizationGuid string `json:"organization_guid"`
+			} `json:"entity"`
+		}
+		Expect(json.Unmarshal(spaceSession.Out.Contents(), &space)).To(Succeed())
+
+		quotaBody := fmt.Sprintf(`{"name":"%s","organization_guid":"%s","memory_limit":%d,"non_basic_services_allowed":true,"total_services":-1,"total_routes":-1}`,
+			generator.PrefixedRandomName("CATS-SPACE-QUOTA-"), space.Entity.OrganizationGuid, memoryLimitInMb)
+		quotaSession := cf.Cf("curl", "/v2/space_quota_definitions", "-X", "POST", "-d", quotaBody)
+		Expect(quotaSession.Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		var quota struct {
+			Metadata struct {
+				Guid string `json:"guid"`
+			} `json:"metadata"`
+		}
+		Expect(json.Unmarshal(quotaSession.Out.Contents(), &quota)).To(Succeed())
+		Expect(quota.Metadata.Guid).NotTo(BeEmpty())
+		quotaGuid = quota.Metadata.Guid
+
+		Expect(cf.Cf("curl", fmt.Sprintf("/v2/space_quota_definitions/%s/spaces/%s", quotaGuid, spaceGuid), "-X", "PUT").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+	})
+
+	return quotaGuid
+}
+
+func deleteSpaceQuota(quotaGuid, spaceGuid string) {
+	cf.AsUser(context.AdminUserContext(), DEFAULT_TIMEOUT, func() {
+		Expect(cf.Cf("curl", fmt.Sprintf("/v2/space_quota_definitions/%s/spaces/%s", quotaGuid, spaceGuid), "-X", "DELETE").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+		Expect(cf.Cf("curl", fmt.Sprintf("/v2/space_quota_definitions/%s", quotaGuid), "-X", "DELETE").Wait(DEFAULT_TIMEOUT)).To(Exit(0))
+	})
+}